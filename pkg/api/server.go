@@ -0,0 +1,134 @@
+// Package api implements the HTTP surface of watchurl serve: adding and
+// removing watched URLs at runtime, and tailing the event log.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/glog"
+
+	"github.com/the80srobot/watchurl/pkg/daemon"
+	"github.com/the80srobot/watchurl/pkg/eventlog"
+)
+
+// Server exposes a daemon.Manager and eventlog.Log over HTTP.
+type Server struct {
+	Manager *daemon.Manager
+	Log     *eventlog.Log
+}
+
+// Handler returns the mux of routes this Server serves.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/urls", s.handleURLs)
+	mux.HandleFunc("/logs", s.handleLogs)
+	return mux
+}
+
+type urlRequest struct {
+	URL string `json:"url"`
+}
+
+func (s *Server) handleURLs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(s.Manager.URLs())
+
+	case http.MethodPost:
+		var req urlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+			http.Error(w, "expected JSON body with a non-empty \"url\"", http.StatusBadRequest)
+			return
+		}
+		if err := s.Manager.Add(r.Context(), req.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		addr := r.URL.Query().Get("url")
+		if addr == "" {
+			http.Error(w, "expected ?url=", http.StatusBadRequest)
+			return
+		}
+		if err := s.Manager.Remove(addr); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleLogs implements GET /logs?after=<id>: it first replays every
+// persisted event with ID greater than after, then streams newly appended
+// events as they happen, so a disconnected client can resume exactly where
+// it left off by passing the last ID it saw.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var after uint64
+	if v := r.URL.Query().Get("after"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "after must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		after = parsed
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	// Subscribe before the replay, so events appended while we're still
+	// reading the backlog aren't lost between the two steps.
+	live, unsubscribe := s.Log.Subscribe()
+	defer unsubscribe()
+
+	backlog, err := s.Log.ReadFrom(after)
+	if err != nil {
+		glog.Warningf("Reading event log backlog: %v", err)
+		return
+	}
+	for _, e := range backlog {
+		if err := eventlog.WriteFramed(w, e); err != nil {
+			return
+		}
+		after = e.ID
+	}
+	flusher.Flush()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-live:
+			if !ok {
+				return
+			}
+			if e.ID <= after {
+				// Already sent as part of the backlog.
+				continue
+			}
+			if err := eventlog.WriteFramed(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/the80srobot/watchurl/pkg/daemon"
+	"github.com/the80srobot/watchurl/pkg/eventlog"
+	"github.com/the80srobot/watchurl/pkg/extract"
+	"github.com/the80srobot/watchurl/pkg/store"
+)
+
+// TestAddOutlivesRequestContext guards against the watch loop being tied to
+// the context of the HTTP request that started it: net/http cancels
+// r.Context() as soon as the handler returns, and the handler for POST
+// /urls returns almost immediately after calling Manager.Add.
+func TestAddOutlivesRequestContext(t *testing.T) {
+	var hits int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	st, err := store.New(t.TempDir(), store.FormatText)
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	ex, err := extract.New("html2text", "")
+	if err != nil {
+		t.Fatalf("extract.New: %v", err)
+	}
+
+	mgr := daemon.NewManager(daemon.Config{
+		Store:     st,
+		Extractor: ex,
+		Every:     10 * time.Millisecond,
+	})
+	defer mgr.Shutdown()
+	srv := &Server{Manager: mgr, Log: mustOpenLog(t)}
+
+	body, _ := json.Marshal(urlRequest{URL: target.URL})
+	req := httptest.NewRequest(http.MethodPost, "/urls", bytes.NewReader(body))
+
+	// Simulate net/http cancelling the request's context the instant the
+	// handler returns, which happens well before the first tick of a watch
+	// loop started with Every: 10ms.
+	ctx, cancel := context.WithCancel(context.Background())
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+	srv.handleURLs(rec, req)
+	cancel()
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST /urls status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		if atomic.LoadInt32(&hits) > 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("target hit %d times after the request context was cancelled, want > 1", hits)
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func mustOpenLog(t *testing.T) *eventlog.Log {
+	t.Helper()
+	l, err := eventlog.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("eventlog.Open: %v", err)
+	}
+	return l
+}
@@ -0,0 +1,144 @@
+// Package daemon runs watchurl's watch loops under a long-lived process,
+// with URLs that can be added and removed at runtime (see watchurl serve).
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/the80srobot/watchurl/pkg/eventlog"
+	"github.com/the80srobot/watchurl/pkg/extract"
+	"github.com/the80srobot/watchurl/pkg/fetcher"
+	"github.com/the80srobot/watchurl/pkg/normalize"
+	"github.com/the80srobot/watchurl/pkg/policy"
+	"github.com/the80srobot/watchurl/pkg/store"
+	"github.com/the80srobot/watchurl/pkg/watcher"
+)
+
+// Config holds everything a watch loop needs other than the URL itself.
+type Config struct {
+	Store         store.Store
+	Extractor     extract.Extractor
+	FetcherName   string
+	Every         time.Duration
+	Jitter        time.Duration
+	LogFullDiff   bool
+	DiffAlgorithm watcher.Algorithm
+	Log           *eventlog.Log
+	// Notify, if set, is passed through to every Watcher's Notify field.
+	Notify func(addr, diff string, edits int)
+	// Policy, if set, wraps every URL's Fetcher with robots.txt, concurrency
+	// and retry/backoff behaviour.
+	Policy *policy.Policy
+	// Normalizers, if set, maps a watched URL to the normalize.Pipeline that
+	// should clean up its content before diffing.
+	Normalizers map[string]*normalize.Pipeline
+}
+
+// Manager runs one Watcher goroutine per watched URL and lets callers add or
+// remove URLs while it is running.
+type Manager struct {
+	cfg Config
+
+	// baseCtx is the parent of every watch loop's context. It lives for as
+	// long as the Manager itself, so a watch loop started on behalf of a
+	// short-lived caller (e.g. an HTTP request) keeps running after that
+	// caller is gone; only Remove or Shutdown stops it.
+	baseCtx    context.Context
+	baseCancel context.CancelFunc
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager returns a Manager that has not yet started watching anything.
+func NewManager(cfg Config) *Manager {
+	baseCtx, baseCancel := context.WithCancel(context.Background())
+	return &Manager{
+		cfg:        cfg,
+		baseCtx:    baseCtx,
+		baseCancel: baseCancel,
+		cancels:    map[string]context.CancelFunc{},
+	}
+}
+
+// Add starts watching addr, if it isn't already being watched. The watch
+// loop's lifetime is tied to the Manager (see baseCtx), not to ctx, so it
+// survives well past the return of a short-lived caller such as an HTTP
+// handler. ctx is not used.
+func (m *Manager) Add(ctx context.Context, addr string) error {
+	fe, err := fetcher.New(addr, m.cfg.FetcherName)
+	if err != nil {
+		return err
+	}
+	if m.cfg.Policy != nil {
+		fe = m.cfg.Policy.Wrap(addr, fe)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.cancels[addr]; ok {
+		return fmt.Errorf("daemon: already watching %s", addr)
+	}
+
+	watchCtx, cancel := context.WithCancel(m.baseCtx)
+	m.cancels[addr] = cancel
+
+	w := &watcher.Watcher{
+		Fetcher:       fe,
+		Extractor:     m.cfg.Extractor,
+		Store:         m.cfg.Store,
+		Normalizer:    m.cfg.Normalizers[addr],
+		DiffAlgorithm: m.cfg.DiffAlgorithm,
+		LogFullDiff:   m.cfg.LogFullDiff,
+		Log:           m.cfg.Log,
+		Notify:        m.cfg.Notify,
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		w.Watch(watchCtx, addr, m.cfg.Every, m.cfg.Jitter)
+	}()
+	return nil
+}
+
+// Remove stops watching addr.
+func (m *Manager) Remove(addr string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cancel, ok := m.cancels[addr]
+	if !ok {
+		return fmt.Errorf("daemon: not watching %s", addr)
+	}
+	cancel()
+	delete(m.cancels, addr)
+	return nil
+}
+
+// URLs returns the currently watched URLs, sorted.
+func (m *Manager) URLs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	urls := make([]string, 0, len(m.cancels))
+	for addr := range m.cancels {
+		urls = append(urls, addr)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Shutdown cancels every watch loop and waits for them to return.
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	for _, cancel := range m.cancels {
+		cancel()
+	}
+	m.mu.Unlock()
+	m.baseCancel()
+	m.wg.Wait()
+}
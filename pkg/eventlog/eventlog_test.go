@@ -0,0 +1,75 @@
+package eventlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLogAppendAndReadFrom(t *testing.T) {
+	l, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	var ids []uint64
+	for _, typ := range []Type{FetchStarted, FetchOK, Diff} {
+		e, err := l.Append(Event{Type: typ, URL: "https://example.com/a"})
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Fatalf("IDs = %v, want monotonically increasing from 1", ids)
+	}
+
+	events, err := l.ReadFrom(1)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if len(events) != 2 || events[0].Type != FetchOK || events[1].Type != Diff {
+		t.Fatalf("ReadFrom(1) = %+v, want [FetchOK, Diff]", events)
+	}
+}
+
+func TestLogReopenResumesLastID(t *testing.T) {
+	dir := t.TempDir()
+	l1, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := l1.Append(Event{Type: FetchStarted}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if _, err := l1.Append(Event{Type: FetchOK}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	l2, err := Open(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	e, err := l2.Append(Event{Type: Diff})
+	if err != nil {
+		t.Fatalf("Append after reopen: %v", err)
+	}
+	if e.ID != 3 {
+		t.Errorf("ID after reopen = %d, want 3", e.ID)
+	}
+}
+
+func TestWriteReadFramedRoundTrip(t *testing.T) {
+	want := Event{ID: 42, Type: Error, URL: "https://example.com/a", Time: time.Unix(1, 0).UTC(), Error: "boom"}
+	var buf bytes.Buffer
+	if err := WriteFramed(&buf, want); err != nil {
+		t.Fatalf("WriteFramed: %v", err)
+	}
+	got, err := ReadFramed(&buf)
+	if err != nil {
+		t.Fatalf("ReadFramed: %v", err)
+	}
+	if got != want {
+		t.Errorf("ReadFramed = %+v, want %+v", got, want)
+	}
+}
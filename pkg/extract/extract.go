@@ -0,0 +1,40 @@
+// Package extract turns the raw bytes a fetcher.Fetcher retrieved into plain
+// text suitable for diffing, via a pluggable Extractor.
+package extract
+
+// Extractor narrows a fetched document down to the text that should be
+// diffed between snapshots.
+type Extractor interface {
+	Extract(body []byte) (string, error)
+}
+
+// Factory builds an Extractor for a given selector/pattern argument (the
+// value of --selector, or the empty string if the backend takes none).
+type Factory func(arg string) (Extractor, error)
+
+var registry = map[string]Factory{}
+
+// Register associates an extractor backend with the name used in
+// --extractor=name.
+func Register(name string, f Factory) {
+	registry[name] = f
+}
+
+// New builds the named Extractor, passing it arg (e.g. a CSS selector or
+// regular expression). name defaults to "html2text" when empty.
+func New(name, arg string) (Extractor, error) {
+	if name == "" {
+		name = "html2text"
+	}
+	factory, ok := registry[name]
+	if !ok {
+		return nil, &unknownBackendError{name}
+	}
+	return factory(arg)
+}
+
+type unknownBackendError struct{ name string }
+
+func (e *unknownBackendError) Error() string {
+	return "extract: unknown backend " + e.name
+}
@@ -0,0 +1,15 @@
+package extract
+
+import "jaytaylor.com/html2text"
+
+func init() {
+	Register("html2text", func(arg string) (Extractor, error) { return html2textExtractor{}, nil })
+}
+
+// html2textExtractor renders the whole document down to readable plain text.
+// It is the default extractor and matches watchurl's original behaviour.
+type html2textExtractor struct{}
+
+func (html2textExtractor) Extract(body []byte) (string, error) {
+	return html2text.FromString(string(body), html2text.Options{OmitLinks: true})
+}
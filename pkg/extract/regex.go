@@ -0,0 +1,41 @@
+package extract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	Register("regex", func(arg string) (Extractor, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("extract: regex backend requires --selector")
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("extract: compiling regex %q: %w", arg, err)
+		}
+		return &regexExtractor{re: re}, nil
+	})
+}
+
+// regexExtractor keeps only the text matched by a regular expression (or its
+// first capture group, if the expression has one), one match per line.
+type regexExtractor struct {
+	re *regexp.Regexp
+}
+
+func (e *regexExtractor) Extract(body []byte) (string, error) {
+	matches := e.re.FindAllStringSubmatch(string(body), -1)
+
+	var sb strings.Builder
+	for _, m := range matches {
+		if len(m) > 1 {
+			sb.WriteString(m[1])
+		} else {
+			sb.WriteString(m[0])
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,38 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func init() {
+	Register("selector", func(arg string) (Extractor, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("extract: selector backend requires --selector")
+		}
+		return &selectorExtractor{selector: arg}, nil
+	})
+}
+
+// selectorExtractor keeps only the text of elements matching a CSS selector,
+// e.g. "main .content", so unrelated chrome around the interesting part of
+// the page doesn't generate noise in diffs.
+type selectorExtractor struct {
+	selector string
+}
+
+func (e *selectorExtractor) Extract(body []byte) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	doc.Find(e.selector).Each(func(_ int, sel *goquery.Selection) {
+		sb.WriteString(strings.TrimSpace(sel.Text()))
+		sb.WriteByte('\n')
+	})
+	return sb.String(), nil
+}
@@ -0,0 +1,40 @@
+package extract
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antchfx/htmlquery"
+)
+
+func init() {
+	Register("xpath", func(arg string) (Extractor, error) {
+		if arg == "" {
+			return nil, fmt.Errorf("extract: xpath backend requires --selector")
+		}
+		return &xpathExtractor{expr: arg}, nil
+	})
+}
+
+// xpathExtractor keeps only the text of nodes matching an XPath expression.
+type xpathExtractor struct {
+	expr string
+}
+
+func (e *xpathExtractor) Extract(body []byte) (string, error) {
+	doc, err := htmlquery.Parse(strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	nodes, err := htmlquery.QueryAll(doc, e.expr)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		sb.WriteString(strings.TrimSpace(htmlquery.InnerText(n)))
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,46 @@
+package fetcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func init() {
+	Register("chrome", func() Fetcher { return &Chrome{} })
+}
+
+// Chrome renders addr in headless Chrome via chromedp and returns the
+// resulting DOM, for sites whose content is populated by JavaScript after
+// load. Select it with --fetcher=chrome.
+type Chrome struct {
+	// Wait is how long to let the page run its scripts before the DOM is
+	// captured. Zero means 2 seconds.
+	Wait time.Duration
+}
+
+func (f *Chrome) Fetch(ctx context.Context, addr string) (*Response, error) {
+	wait := f.Wait
+	if wait == 0 {
+		wait = 2 * time.Second
+	}
+
+	ctx, cancel := chromedp.NewContext(ctx)
+	defer cancel()
+
+	var html string
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(addr),
+		chromedp.Sleep(wait),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Body:      []byte(html),
+		FetchedAt: time.Now(),
+	}, nil
+}
@@ -0,0 +1,49 @@
+// Package fetcher defines the pluggable backends watchurl uses to retrieve
+// the raw content of a watched URL. A Fetcher is selected either by the
+// scheme of the URL (http/https, rss/atom, json) or by an explicit
+// --fetcher= override, via the registry in registry.go.
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Response is the raw result of a single fetch, independent of how the
+// content will later be turned into comparable text by pkg/extract.
+type Response struct {
+	// StatusCode is the backend-specific status code, e.g. the HTTP status.
+	// Backends that have no notion of a status code (e.g. headless
+	// rendering) should leave this at 0.
+	StatusCode int
+	// Header carries any protocol-level metadata the backend captured, such
+	// as HTTP response headers (including ETag/Last-Modified, used by the
+	// conditional-GET policy).
+	Header http.Header
+	// Body is the raw, unprocessed payload: HTML, an RSS feed, a JSON
+	// document, etc. It is up to the matching Extractor to interpret it.
+	Body []byte
+	// FetchedAt is when the fetch completed.
+	FetchedAt time.Time
+	// NotModified is set by a ConditionalFetcher when the server confirmed
+	// (e.g. via HTTP 304) that the content hasn't changed since the given
+	// ETag/Last-Modified. Body is empty in that case.
+	NotModified bool
+}
+
+// Fetcher retrieves the current content behind addr. Implementations must be
+// safe for concurrent use, since watchurl runs one watch loop per watched
+// URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, addr string) (*Response, error)
+}
+
+// ConditionalFetcher is implemented by backends that can skip downloading
+// the body when the server confirms nothing changed since a previous
+// ETag/Last-Modified pair (see pkg/policy). etag and lastModified may be
+// empty, in which case the fetch is unconditional.
+type ConditionalFetcher interface {
+	Fetcher
+	FetchIfModified(ctx context.Context, addr, etag, lastModified string) (*Response, error)
+}
@@ -0,0 +1,101 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHttpize(t *testing.T) {
+	cases := []struct {
+		addr string
+		want string
+	}{
+		{"rss://example.com/feed.xml", "https://example.com/feed.xml"},
+		{"atom://example.com/feed.xml", "https://example.com/feed.xml"},
+		{"json://example.com/status.json#items[0].price", "https://example.com/status.json#items[0].price"},
+		{"https://example.com/a", "https://example.com/a"},
+	}
+	for _, c := range cases {
+		got, err := httpize(c.addr)
+		if err != nil {
+			t.Fatalf("httpize(%q): %v", c.addr, err)
+		}
+		if got != c.want {
+			t.Errorf("httpize(%q) = %q, want %q", c.addr, got, c.want)
+		}
+	}
+}
+
+func TestJSONFetchJMESPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[{"price":10},{"price":20}]}`))
+	}))
+	defer srv.Close()
+
+	f := &JSON{HTTP: &HTTP{Client: srv.Client()}}
+	resp, err := f.Fetch(context.Background(), srv.URL+"#items[0].price")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got, want := string(resp.Body), "10\n"; got != want {
+		t.Errorf("Body = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFetchNoExpressionPassesBodyThrough(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+
+	f := &JSON{HTTP: &HTTP{Client: srv.Client()}}
+	resp, err := f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got, want := string(resp.Body), `{"a":1}`; got != want {
+		t.Errorf("Body = %q, want %q", got, want)
+	}
+}
+
+func TestJSONFetchIfModifiedForwardsConditionalGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+
+	f := &JSON{HTTP: &HTTP{Client: srv.Client()}}
+	resp, err := f.FetchIfModified(context.Background(), srv.URL, `"v1"`, "")
+	if err != nil {
+		t.Fatalf("FetchIfModified: %v", err)
+	}
+	if !resp.NotModified {
+		t.Errorf("NotModified = false, want true")
+	}
+}
+
+func TestRSSFetchIfModifiedForwardsConditionalGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`<rss><channel><item><title>hi</title></item></channel></rss>`))
+	}))
+	defer srv.Close()
+
+	f := &RSS{HTTP: &HTTP{Client: srv.Client()}}
+	resp, err := f.FetchIfModified(context.Background(), srv.URL, `"v1"`, "")
+	if err != nil {
+		t.Fatalf("FetchIfModified: %v", err)
+	}
+	if !resp.NotModified {
+		t.Errorf("NotModified = false, want true")
+	}
+}
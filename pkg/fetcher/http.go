@@ -0,0 +1,63 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+func init() {
+	Register("http", func() Fetcher { return &HTTP{Client: http.DefaultClient} })
+}
+
+// HTTP fetches addr with a plain GET. It is the default backend for
+// http:// and https:// URLs.
+type HTTP struct {
+	Client *http.Client
+}
+
+func (f *HTTP) Fetch(ctx context.Context, addr string) (*Response, error) {
+	return f.FetchIfModified(ctx, addr, "", "")
+}
+
+// FetchIfModified sends If-None-Match/If-Modified-Since when etag or
+// lastModified are non-empty, and reports Response.NotModified instead of
+// downloading the body if the server replies with 304.
+func (f *HTTP) FetchIfModified(ctx context.Context, addr, etag, lastModified string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &Response{StatusCode: resp.StatusCode, Header: resp.Header, FetchedAt: time.Now(), NotModified: true}, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		FetchedAt:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+func init() {
+	Register("json", func() Fetcher { return &JSON{HTTP: &HTTP{}} })
+}
+
+// JSON fetches a JSON document over HTTP and narrows it down to a single
+// value with a JMESPath expression, so that only the fields the user cares
+// about are diffed. The expression is read from the URL's #fragment, e.g.
+// "https://api.example.com/status.json#items[0].price".
+type JSON struct {
+	HTTP *HTTP
+}
+
+func (f *JSON) Fetch(ctx context.Context, addr string) (*Response, error) {
+	return f.FetchIfModified(ctx, addr, "", "")
+}
+
+// FetchIfModified forwards to the embedded HTTP backend, so that JSON feeds
+// also benefit from conditional GETs (see policy.Policy), then applies the
+// JMESPath narrowing on top of whatever it returned.
+func (f *JSON) FetchIfModified(ctx context.Context, addr, etag, lastModified string) (*Response, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	expr := u.Fragment
+	u.Fragment = ""
+
+	httpAddr, err := httpize(u.String())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.HTTP.FetchIfModified(ctx, httpAddr, etag, lastModified)
+	if err != nil {
+		return nil, err
+	}
+	if resp.NotModified || expr == "" {
+		return resp, nil
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(resp.Body, &doc); err != nil {
+		return nil, fmt.Errorf("fetcher: decoding JSON from %s: %w", addr, err)
+	}
+	result, err := jmespath.Search(expr, doc)
+	if err != nil {
+		return nil, fmt.Errorf("fetcher: evaluating JMESPath %q: %w", expr, err)
+	}
+
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return nil, err
+	}
+	resp.Body = []byte(sb.String())
+	return resp, nil
+}
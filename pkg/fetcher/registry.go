@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Factory builds a Fetcher. Factories are cheap to call repeatedly; most
+// backends are stateless or hold only a shared *http.Client.
+type Factory func() Fetcher
+
+var registry = map[string]Factory{}
+
+// Register associates a backend with a name, so it can be selected either by
+// --fetcher=name or, via schemeDefaults, as the default for a URL scheme.
+//
+// Register is expected to be called from init() in the files implementing
+// each backend.
+func Register(name string, f Factory) {
+	registry[name] = f
+}
+
+// schemeDefaults maps a URL scheme to the backend name used when no
+// --fetcher override is given.
+var schemeDefaults = map[string]string{
+	"http":  "http",
+	"https": "http",
+	"rss":   "rss",
+	"atom":  "rss",
+	"json":  "json",
+}
+
+// New returns the Fetcher to use for addr. If name is non-empty it forces
+// that backend; otherwise the backend is chosen from the URL's scheme.
+func New(addr, name string) (Fetcher, error) {
+	if name == "" {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("fetcher: parsing %q: %w", addr, err)
+		}
+		var ok bool
+		name, ok = schemeDefaults[u.Scheme]
+		if !ok {
+			return nil, fmt.Errorf("fetcher: no default backend for scheme %q (use --fetcher=)", u.Scheme)
+		}
+	}
+
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("fetcher: unknown backend %q", name)
+	}
+	return factory(), nil
+}
+
+// httpize rewrites the pseudo-schemes in schemeDefaults (rss, atom, json) to
+// https, since they only exist to pick a backend and net/http rejects any
+// scheme it doesn't recognize.
+func httpize(addr string) (string, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", fmt.Errorf("fetcher: parsing %q: %w", addr, err)
+	}
+	switch u.Scheme {
+	case "rss", "atom", "json":
+		u.Scheme = "https"
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,77 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Register("rss", func() Fetcher { return &RSS{HTTP: &HTTP{}} })
+}
+
+// rssFeed covers just enough of RSS 2.0 and Atom to render titles and
+// summaries for diffing; it is deliberately not a full feed parser.
+type rssFeed struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Entries []rssItem `xml:"entry"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Summary string `xml:"description"`
+	Content string `xml:"summary"`
+}
+
+// RSS fetches an RSS or Atom feed over HTTP and flattens it to a plain-text
+// list of "title: summary" lines, so that new/changed/removed entries show
+// up as ordinary line-based diffs.
+type RSS struct {
+	HTTP *HTTP
+}
+
+func (f *RSS) Fetch(ctx context.Context, addr string) (*Response, error) {
+	return f.FetchIfModified(ctx, addr, "", "")
+}
+
+// FetchIfModified forwards to the embedded HTTP backend, so that feeds also
+// benefit from conditional GETs (see policy.Policy), then flattens whatever
+// it returned to the title/summary text format.
+func (f *RSS) FetchIfModified(ctx context.Context, addr, etag, lastModified string) (*Response, error) {
+	httpAddr, err := httpize(addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.HTTP.FetchIfModified(ctx, httpAddr, etag, lastModified)
+	if err != nil {
+		return nil, err
+	}
+	if resp.NotModified {
+		return resp, nil
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(resp.Body, &feed); err != nil {
+		return nil, fmt.Errorf("fetcher: parsing feed %s: %w", addr, err)
+	}
+
+	items := feed.Channel.Items
+	if len(items) == 0 {
+		items = feed.Entries
+	}
+
+	var sb strings.Builder
+	for _, item := range items {
+		summary := item.Summary
+		if summary == "" {
+			summary = item.Content
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", item.Title, summary)
+	}
+
+	resp.Body = []byte(sb.String())
+	return resp, nil
+}
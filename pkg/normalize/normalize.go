@@ -0,0 +1,131 @@
+// Package normalize strips or masks volatile parts of a fetched page before
+// it's diffed, so that rotating ad slots, timestamps or CSRF tokens don't
+// show up as spurious changes.
+package normalize
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Substitution replaces every match of Pattern (a regular expression) with
+// Replacement (which may use $1-style references to capture groups).
+type Substitution struct {
+	Pattern     string `yaml:"pattern"`
+	Replacement string `yaml:"replacement"`
+}
+
+// Config describes the normalization applied to a single URL. It is the
+// Normalize field of notify.Route, so it's configured alongside notification
+// routes in the same YAML file.
+type Config struct {
+	// ExcludeSelectors removes matching elements (e.g. ads, timestamps)
+	// from the HTML before extraction.
+	ExcludeSelectors []string `yaml:"exclude_selectors"`
+	// Readability keeps only the page's main content, dropping boilerplate
+	// (navigation, sidebars, footers) the same way a reader-mode extension
+	// would.
+	Readability bool `yaml:"readability"`
+	// RegexSubstitutions run, in order, on the extracted text.
+	RegexSubstitutions []Substitution `yaml:"regex_substitutions"`
+	// CollapseWhitespace replaces every run of whitespace with a single
+	// space, so reflowed text doesn't look different.
+	CollapseWhitespace bool `yaml:"collapse_whitespace"`
+	// IgnoreCase lowercases the text before diffing.
+	IgnoreCase bool `yaml:"ignore_case"`
+}
+
+// Pipeline is a Config compiled into ready-to-run steps.
+type Pipeline struct {
+	cfg  Config
+	subs []*compiledSub
+}
+
+type compiledSub struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+var whitespaceRE = regexp.MustCompile(`\s+`)
+
+// Build compiles cfg's regular expressions once, so Normalize can run
+// cheaply on every fetch.
+func Build(cfg Config) (*Pipeline, error) {
+	p := &Pipeline{cfg: cfg}
+	for _, s := range cfg.RegexSubstitutions {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.subs = append(p.subs, &compiledSub{re: re, replacement: s.Replacement})
+	}
+	return p, nil
+}
+
+// NormalizeBody runs the HTML-level steps (ExcludeSelectors, Readability) on
+// body, before it reaches the configured Extractor.
+func (p *Pipeline) NormalizeBody(body []byte) ([]byte, error) {
+	if p == nil || (len(p.cfg.ExcludeSelectors) == 0 && !p.cfg.Readability) {
+		return body, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	for _, sel := range p.cfg.ExcludeSelectors {
+		doc.Find(sel).Remove()
+	}
+	if p.cfg.Readability {
+		mainContent(doc)
+	}
+
+	html, err := doc.Html()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(html), nil
+}
+
+// mainContent trims doc down to the element most likely to be the article
+// body: a <main> or <article> tag if present, otherwise the <div> or
+// <section> with the most text, dropping everything else. This is a deliberately
+// simple heuristic, not a full Readability port.
+func mainContent(doc *goquery.Document) {
+	best := doc.Find("main, article").First()
+	if best.Length() == 0 {
+		bestLen := 0
+		doc.Find("div, section").Each(func(_ int, s *goquery.Selection) {
+			if n := len(strings.TrimSpace(s.Text())); n > bestLen {
+				bestLen = n
+				best = s
+			}
+		})
+	}
+	if best.Length() == 0 {
+		return
+	}
+	if html, err := best.Html(); err == nil {
+		doc.Find("body").SetHtml(html)
+	}
+}
+
+// NormalizeText runs the text-level steps (RegexSubstitutions,
+// CollapseWhitespace, IgnoreCase) on already-extracted text.
+func (p *Pipeline) NormalizeText(text string) string {
+	if p == nil {
+		return text
+	}
+	for _, s := range p.subs {
+		text = s.re.ReplaceAllString(text, s.replacement)
+	}
+	if p.cfg.CollapseWhitespace {
+		text = strings.TrimSpace(whitespaceRE.ReplaceAllString(text, " "))
+	}
+	if p.cfg.IgnoreCase {
+		text = strings.ToLower(text)
+	}
+	return text
+}
@@ -0,0 +1,86 @@
+package normalize
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTextRegexSubstitutions(t *testing.T) {
+	p, err := Build(Config{
+		RegexSubstitutions: []Substitution{
+			{Pattern: `\d{4}-\d{2}-\d{2}`, Replacement: "DATE"},
+			{Pattern: `token=[a-z0-9]+`, Replacement: "token=REDACTED"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	got := p.NormalizeText("Published 2026-07-28, see link?token=abc123")
+	want := "Published DATE, see link?token=REDACTED"
+	if got != want {
+		t.Errorf("NormalizeText = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeTextCollapseWhitespaceAndIgnoreCase(t *testing.T) {
+	p, err := Build(Config{CollapseWhitespace: true, IgnoreCase: true})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	got := p.NormalizeText("  Hello   \n\tWorld  ")
+	want := "hello world"
+	if got != want {
+		t.Errorf("NormalizeText = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeTextOrderSubstitutionsBeforeCollapse(t *testing.T) {
+	p, err := Build(Config{
+		RegexSubstitutions: []Substitution{{Pattern: `foo`, Replacement: "  bar  "}},
+		CollapseWhitespace: true,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	got := p.NormalizeText("x foo y")
+	want := "x bar y"
+	if got != want {
+		t.Errorf("NormalizeText = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeTextNilPipelineIsNoop(t *testing.T) {
+	var p *Pipeline
+	if got := p.NormalizeText("unchanged"); got != "unchanged" {
+		t.Errorf("NormalizeText on nil *Pipeline = %q, want %q", got, "unchanged")
+	}
+}
+
+func TestBuildInvalidRegexErrors(t *testing.T) {
+	_, err := Build(Config{RegexSubstitutions: []Substitution{{Pattern: `(`, Replacement: "x"}}})
+	if err == nil {
+		t.Fatal("Build: got nil error for an invalid regex")
+	}
+}
+
+func TestNormalizeBodyExcludeSelectors(t *testing.T) {
+	p, err := Build(Config{ExcludeSelectors: []string{".ad"}})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	body := []byte(`<html><body><p>keep</p><div class="ad">drop me</div></body></html>`)
+	got, err := p.NormalizeBody(body)
+	if err != nil {
+		t.Fatalf("NormalizeBody: %v", err)
+	}
+	if strings.Contains(string(got), "drop me") {
+		t.Errorf("NormalizeBody output still contains excluded content: %s", got)
+	}
+	if !strings.Contains(string(got), "keep") {
+		t.Errorf("NormalizeBody output dropped content it shouldn't have: %s", got)
+	}
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func init() {
+	Register("slack", func(opts map[string]string) (Sink, error) { return newChatWebhook("slack", opts) })
+	Register("discord", func(opts map[string]string) (Sink, error) { return newChatWebhook("discord", opts) })
+}
+
+// chatWebhook posts a plain-text message to a Slack or Discord incoming
+// webhook. Both accept the same {"content": "..."} body; Slack also accepts
+// {"text": "..."}, which is what it documents, so we send both keys.
+type chatWebhook struct {
+	kind string
+	url  string
+}
+
+func newChatWebhook(kind string, opts map[string]string) (Sink, error) {
+	url := opts["url"]
+	if url == "" {
+		return nil, fmt.Errorf("notify: %s sink requires a \"url\" option", kind)
+	}
+	return &chatWebhook{kind: kind, url: url}, nil
+}
+
+func (s *chatWebhook) Notify(ctx context.Context, e Event) error {
+	text := fmt.Sprintf("Site updated: %s (%d edits)\n%s", e.URL, e.Edits, e.Diff)
+	body, err := json.Marshal(struct {
+		Text    string `json:"text"`
+		Content string `json:"content"`
+	}{Text: text, Content: text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notify: %s webhook returned %s", s.kind, resp.Status)
+	}
+	return nil
+}
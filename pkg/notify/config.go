@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/the80srobot/watchurl/pkg/normalize"
+)
+
+// Filters decides whether a Route fires for a given Event. Empty fields
+// impose no constraint.
+type Filters struct {
+	// MinEdits requires at least this many edits (see watcher.ReportDiffs).
+	MinEdits int `yaml:"min_edits"`
+	// Contains requires the diff to contain this substring.
+	Contains string `yaml:"contains"`
+	// NotContains rejects the event if the diff contains this substring.
+	NotContains string `yaml:"not_contains"`
+}
+
+func (f Filters) match(e Event) bool {
+	if e.Edits < f.MinEdits {
+		return false
+	}
+	if f.Contains != "" && !strings.Contains(e.Diff, f.Contains) {
+		return false
+	}
+	if f.NotContains != "" && strings.Contains(e.Diff, f.NotContains) {
+		return false
+	}
+	return true
+}
+
+// SinkConfig is one destination a Route notifies, e.g. "type: webhook" plus
+// backend-specific options.
+type SinkConfig struct {
+	Type    string            `yaml:"type"`
+	Options map[string]string `yaml:",inline"`
+}
+
+// Route configures the sinks that should fire for a single watched URL, and
+// (via Normalize) how its content is cleaned up before diffing.
+type Route struct {
+	URL       string `yaml:"url"`
+	Filters   `yaml:",inline"`
+	Sinks     []SinkConfig     `yaml:"sinks"`
+	Normalize normalize.Config `yaml:"normalize"`
+}
+
+// Config is the root of ~/.watchurl/config.yaml.
+type Config struct {
+	Routes []Route `yaml:"routes"`
+}
+
+// Load reads and parses a config file. A missing file is not an error: it
+// returns an empty Config, so that notify configuration remains optional.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("notify: parsing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Router dispatches Events to the Sinks configured for their URL, built
+// once from a Config.
+type Router struct {
+	routes []builtRoute
+}
+
+type builtRoute struct {
+	url     string
+	filters Filters
+	sinks   []Sink
+}
+
+// Build constructs every Sink referenced by cfg, so that New fails fast on a
+// bad config instead of on the first matching event.
+func Build(cfg *Config) (*Router, error) {
+	r := &Router{}
+	for _, route := range cfg.Routes {
+		var sinks []Sink
+		for _, sc := range route.Sinks {
+			sink, err := New(sc.Type, sc.Options)
+			if err != nil {
+				return nil, fmt.Errorf("notify: route %s: %w", route.URL, err)
+			}
+			sinks = append(sinks, sink)
+		}
+		r.routes = append(r.routes, builtRoute{url: route.URL, filters: route.Filters, sinks: sinks})
+	}
+	return r, nil
+}
+
+// Dispatch sends e to every sink whose route matches e.URL and filters. It
+// logs (via the returned error) only the first sink failure it encounters,
+// but still attempts every matching sink.
+func (r *Router) Dispatch(ctx context.Context, e Event) error {
+	var firstErr error
+	for _, route := range r.routes {
+		if route.url != e.URL || !route.filters.match(e) {
+			continue
+		}
+		for _, sink := range route.sinks {
+			if err := sink.Notify(ctx, e); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func optInt(opts map[string]string, key string, def int) int {
+	v, ok := opts[key]
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
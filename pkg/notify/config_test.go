@@ -0,0 +1,232 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFiltersMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		filters Filters
+		event   Event
+		want    bool
+	}{
+		{"no constraints", Filters{}, Event{Edits: 0, Diff: ""}, true},
+		{"min edits met", Filters{MinEdits: 5}, Event{Edits: 5}, true},
+		{"min edits not met", Filters{MinEdits: 5}, Event{Edits: 4}, false},
+		{"contains satisfied", Filters{Contains: "sold out"}, Event{Diff: "item is sold out today"}, true},
+		{"contains not satisfied", Filters{Contains: "sold out"}, Event{Diff: "back in stock"}, false},
+		{"not_contains rejects a match", Filters{NotContains: "cookie"}, Event{Diff: "accept our cookie banner"}, false},
+		{"not_contains allows a non-match", Filters{NotContains: "cookie"}, Event{Diff: "price dropped"}, true},
+		{
+			"all three combined",
+			Filters{MinEdits: 2, Contains: "price", NotContains: "cookie"},
+			Event{Edits: 3, Diff: "price dropped, no cookie mention"},
+			false, // "no cookie mention" still contains "cookie"
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.filters.match(c.event); got != c.want {
+				t.Errorf("match(%+v) = %v, want %v", c.event, got, c.want)
+			}
+		})
+	}
+}
+
+// recordingSink records every Event it's asked to deliver, optionally
+// failing with a fixed error.
+type recordingSink struct {
+	name      string
+	err       error
+	delivered *[]string
+}
+
+func (s *recordingSink) Notify(ctx context.Context, e Event) error {
+	*s.delivered = append(*s.delivered, s.name)
+	return s.err
+}
+
+func newRecordingFactory(delivered *[]string) Factory {
+	return func(opts map[string]string) (Sink, error) {
+		name := opts["name"]
+		if name == "fail-build" {
+			return nil, fmt.Errorf("notify: recording sink configured to fail build")
+		}
+		var err error
+		if name == "fail-notify" {
+			err = fmt.Errorf("notify: recording sink configured to fail")
+		}
+		return &recordingSink{name: name, err: err, delivered: delivered}, nil
+	}
+}
+
+func TestBuildAndDispatchFiresEverySinkOnMatchingRoute(t *testing.T) {
+	var delivered []string
+	Register("recording-test", newRecordingFactory(&delivered))
+
+	cfg := &Config{Routes: []Route{
+		{
+			URL: "https://example.com/a",
+			Sinks: []SinkConfig{
+				{Type: "recording-test", Options: map[string]string{"name": "a1"}},
+				{Type: "recording-test", Options: map[string]string{"name": "a2"}},
+			},
+		},
+		{
+			URL: "https://example.com/b",
+			Sinks: []SinkConfig{
+				{Type: "recording-test", Options: map[string]string{"name": "b1"}},
+			},
+		},
+	}}
+
+	r, err := Build(cfg)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if err := r.Dispatch(context.Background(), Event{URL: "https://example.com/a"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if want := []string{"a1", "a2"}; !equalStrings(delivered, want) {
+		t.Errorf("delivered = %v, want %v", delivered, want)
+	}
+}
+
+func TestBuildFailsFastOnBadSinkConfig(t *testing.T) {
+	var delivered []string
+	Register("recording-test", newRecordingFactory(&delivered))
+
+	cfg := &Config{Routes: []Route{{
+		URL:   "https://example.com/a",
+		Sinks: []SinkConfig{{Type: "recording-test", Options: map[string]string{"name": "fail-build"}}},
+	}}}
+
+	if _, err := Build(cfg); err == nil {
+		t.Fatal("Build: got nil error, want one from the failing sink factory")
+	}
+}
+
+func TestDispatchSkipsNonMatchingURL(t *testing.T) {
+	var delivered []string
+	Register("recording-test", newRecordingFactory(&delivered))
+
+	cfg := &Config{Routes: []Route{{
+		URL:   "https://example.com/a",
+		Sinks: []SinkConfig{{Type: "recording-test", Options: map[string]string{"name": "a1"}}},
+	}}}
+	r, err := Build(cfg)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if err := r.Dispatch(context.Background(), Event{URL: "https://example.com/other"}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(delivered) != 0 {
+		t.Errorf("delivered = %v, want none (URL doesn't match any route)", delivered)
+	}
+}
+
+func TestDispatchSkipsRouteWhenFiltersDontMatch(t *testing.T) {
+	var delivered []string
+	Register("recording-test", newRecordingFactory(&delivered))
+
+	cfg := &Config{Routes: []Route{{
+		URL:     "https://example.com/a",
+		Filters: Filters{MinEdits: 10},
+		Sinks:   []SinkConfig{{Type: "recording-test", Options: map[string]string{"name": "a1"}}},
+	}}}
+	r, err := Build(cfg)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if err := r.Dispatch(context.Background(), Event{URL: "https://example.com/a", Edits: 1}); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if len(delivered) != 0 {
+		t.Errorf("delivered = %v, want none (edits below min_edits)", delivered)
+	}
+}
+
+func TestDispatchReturnsFirstErrorButStillFiresEverySink(t *testing.T) {
+	var delivered []string
+	Register("recording-test", newRecordingFactory(&delivered))
+
+	cfg := &Config{Routes: []Route{{
+		URL: "https://example.com/a",
+		Sinks: []SinkConfig{
+			{Type: "recording-test", Options: map[string]string{"name": "fail-notify"}},
+			{Type: "recording-test", Options: map[string]string{"name": "a2"}},
+		},
+	}}}
+	r, err := Build(cfg)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	err = r.Dispatch(context.Background(), Event{URL: "https://example.com/a"})
+	if err == nil {
+		t.Fatal("Dispatch: got nil error, want the first sink's failure")
+	}
+	if want := []string{"fail-notify", "a2"}; !equalStrings(delivered, want) {
+		t.Errorf("delivered = %v, want %v (second sink must still fire)", delivered, want)
+	}
+}
+
+// TestRouteYAMLParsesInlineSinksAndOptions guards against a yaml tag typo in
+// Route or SinkConfig silently dropping the sinks list or its options.
+func TestRouteYAMLParsesInlineSinksAndOptions(t *testing.T) {
+	const doc = `
+routes:
+  - url: https://example.com/a
+    min_edits: 3
+    contains: sale
+    sinks:
+      - type: webhook
+        url: https://hooks.example.com/a
+      - type: exec
+        command: notify-send
+`
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(doc), &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(cfg.Routes) != 1 {
+		t.Fatalf("Routes = %d, want 1", len(cfg.Routes))
+	}
+	route := cfg.Routes[0]
+	if route.URL != "https://example.com/a" {
+		t.Errorf("URL = %q, want https://example.com/a", route.URL)
+	}
+	if route.MinEdits != 3 || route.Contains != "sale" {
+		t.Errorf("Filters = %+v, want MinEdits: 3, Contains: sale", route.Filters)
+	}
+	if len(route.Sinks) != 2 {
+		t.Fatalf("Sinks = %d, want 2", len(route.Sinks))
+	}
+	if route.Sinks[0].Type != "webhook" || route.Sinks[0].Options["url"] != "https://hooks.example.com/a" {
+		t.Errorf("Sinks[0] = %+v, want type webhook with url option", route.Sinks[0])
+	}
+	if route.Sinks[1].Type != "exec" || route.Sinks[1].Options["command"] != "notify-send" {
+		t.Errorf("Sinks[1] = %+v, want type exec with command option", route.Sinks[1])
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
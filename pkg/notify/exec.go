@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	Register("exec", func(opts map[string]string) (Sink, error) {
+		command := opts["command"]
+		if command == "" {
+			return nil, fmt.Errorf("notify: exec sink requires a \"command\" option")
+		}
+		return &execSink{command: command}, nil
+	})
+}
+
+// execSink runs an arbitrary shell command for hooks like xdg-open, passing
+// the event as WATCHURL_URL/WATCHURL_EDITS/WATCHURL_DIFF environment
+// variables so the command doesn't need its own flag parsing.
+type execSink struct {
+	command string
+}
+
+func (s *execSink) Notify(ctx context.Context, e Event) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.command)
+	cmd.Env = append(os.Environ(),
+		"WATCHURL_URL="+e.URL,
+		fmt.Sprintf("WATCHURL_EDITS=%d", e.Edits),
+		"WATCHURL_DIFF="+e.Diff,
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("notify: exec %q: %w", strings.Fields(s.command), err)
+	}
+	return nil
+}
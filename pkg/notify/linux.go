@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+func init() {
+	Register("libnotify", func(opts map[string]string) (Sink, error) { return linuxSink{}, nil })
+}
+
+// linuxSink displays a desktop notification via libnotify's notify-send
+// CLI, which is present on essentially every Linux desktop.
+type linuxSink struct{}
+
+func (linuxSink) Notify(ctx context.Context, e Event) error {
+	body := fmt.Sprintf("%d edits (check console output)", e.Edits)
+	return exec.CommandContext(ctx, "notify-send", "Site updated: "+e.URL, body).Run()
+}
@@ -0,0 +1,20 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andybrewer/mack"
+)
+
+func init() {
+	Register("macos", func(opts map[string]string) (Sink, error) { return macosSink{}, nil })
+}
+
+// macosSink displays a macOS desktop notification via mack. It is a no-op
+// (mack returns an error) on any other platform.
+type macosSink struct{}
+
+func (macosSink) Notify(ctx context.Context, e Event) error {
+	return mack.Notify("Site updated", e.URL, fmt.Sprintf("%d edits (check console output)", e.Edits), "Ping")
+}
@@ -0,0 +1,48 @@
+// Package notify routes site-update events to pluggable destinations
+// ("sinks"): desktop notifications, webhooks, email, chat apps or an
+// arbitrary command. Which sinks fire for a given URL, and under what
+// filters, is configured by pkg/notify/config.go.
+package notify
+
+import "context"
+
+// Event describes a single detected change, passed to every Sink whose
+// route matches.
+type Event struct {
+	URL   string
+	Diff  string
+	Edits int
+}
+
+// Sink delivers an Event somewhere: a desktop notification, a webhook, an
+// email, etc.
+type Sink interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// Factory builds a Sink from the options given in a route's config (e.g. a
+// webhook URL, or an SMTP server address).
+type Factory func(opts map[string]string) (Sink, error)
+
+var registry = map[string]Factory{}
+
+// Register associates a sink backend with the name used as "type:" in
+// config.yaml. Called from init() in the file implementing each backend.
+func Register(name string, f Factory) {
+	registry[name] = f
+}
+
+// New builds the named Sink backend with the given options.
+func New(name string, opts map[string]string) (Sink, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, &unknownSinkError{name}
+	}
+	return factory(opts)
+}
+
+type unknownSinkError struct{ name string }
+
+func (e *unknownSinkError) Error() string {
+	return "notify: unknown sink type " + e.name
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+func init() {
+	Register("smtp", func(opts map[string]string) (Sink, error) {
+		host := opts["host"]
+		from := opts["from"]
+		to := opts["to"]
+		if host == "" || from == "" || to == "" {
+			return nil, fmt.Errorf("notify: smtp sink requires \"host\", \"from\" and \"to\" options")
+		}
+		return &smtpSink{
+			addr: fmt.Sprintf("%s:%d", host, optInt(opts, "port", 25)),
+			from: from,
+			to:   to,
+			user: opts["user"],
+			pass: opts["password"],
+		}, nil
+	})
+}
+
+// smtpSink emails the diff using net/smtp. Authentication is skipped when
+// user/password aren't set, for local/relay SMTP servers that don't need it.
+type smtpSink struct {
+	addr, from, to, user, pass string
+}
+
+func (s *smtpSink) Notify(ctx context.Context, e Event) error {
+	var auth smtp.Auth
+	if s.user != "" {
+		host, _, _ := strings.Cut(s.addr, ":")
+		auth = smtp.PlainAuth("", s.user, s.pass, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: Site updated: %s\r\n\r\n%d edits:\n\n%s\n",
+		s.from, s.to, e.URL, e.Edits, e.Diff)
+	return smtp.SendMail(s.addr, auth, s.from, []string{s.to}, []byte(msg))
+}
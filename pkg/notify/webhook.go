@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func init() {
+	Register("webhook", func(opts map[string]string) (Sink, error) {
+		url := opts["url"]
+		if url == "" {
+			return nil, fmt.Errorf("notify: webhook sink requires a \"url\" option")
+		}
+		return &webhookSink{url: url}, nil
+	})
+}
+
+// webhookSink POSTs a JSON body of {url, edits, diff} to a configured
+// endpoint.
+type webhookSink struct {
+	url string
+}
+
+func (s *webhookSink) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(struct {
+		URL   string `json:"url"`
+		Edits int    `json:"edits"`
+		Diff  string `json:"diff"`
+	}{e.URL, e.Edits, e.Diff})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notify: webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	Register("windows-toast", func(opts map[string]string) (Sink, error) { return windowsSink{}, nil })
+}
+
+// windowsSink displays a toast notification via the BurntToast PowerShell
+// module, if installed (Install-Module BurntToast).
+type windowsSink struct{}
+
+func (windowsSink) Notify(ctx context.Context, e Event) error {
+	body := fmt.Sprintf("%d edits (check console output)", e.Edits)
+	script := fmt.Sprintf("New-BurntToastNotification -Text 'Site updated: %s', '%s'", psQuote(e.URL), psQuote(body))
+	return exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script).Run()
+}
+
+// psQuote escapes s for embedding in a single-quoted PowerShell string
+// literal, where the only special character is the quote itself, escaped by
+// doubling it.
+func psQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -0,0 +1,139 @@
+// Package policy wraps a fetcher.Fetcher with the polite, per-host behaviour
+// a real crawler needs: robots.txt, a concurrency cap, conditional GET
+// against the last stored snapshot, and retry with exponential backoff.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/the80srobot/watchurl/pkg/fetcher"
+	"github.com/the80srobot/watchurl/pkg/store"
+)
+
+// Policy configures the behaviour applied to every fetch.
+type Policy struct {
+	// Store is consulted for the previous snapshot's ETag/Last-Modified
+	// headers, to make conditional GETs.
+	Store store.Store
+
+	// RespectRobots skips URLs disallowed by the host's robots.txt.
+	RespectRobots bool
+	// MaxConcurrencyPerHost caps in-flight fetches per host. Zero means no
+	// cap.
+	MaxConcurrencyPerHost int
+	// RetryBackoffBase is the initial delay before the first retry; it
+	// doubles (plus jitter) on each subsequent attempt.
+	RetryBackoffBase time.Duration
+	// RetryMax is the number of retries after the first attempt. Zero means
+	// the fetch is attempted exactly once.
+	RetryMax int
+
+	mu    sync.Mutex
+	sema  map[string]chan struct{}
+	robot map[string]*robotsRules
+}
+
+// Wrap returns a Fetcher that applies p's policy around inner for a single
+// URL addr.
+func (p *Policy) Wrap(addr string, inner fetcher.Fetcher) fetcher.Fetcher {
+	return &policyFetcher{policy: p, inner: inner, addr: addr}
+}
+
+func (p *Policy) host(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return addr
+	}
+	return u.Host
+}
+
+func (p *Policy) acquire(host string) func() {
+	if p.MaxConcurrencyPerHost <= 0 {
+		return func() {}
+	}
+	p.mu.Lock()
+	if p.sema == nil {
+		p.sema = map[string]chan struct{}{}
+	}
+	sem, ok := p.sema[host]
+	if !ok {
+		sem = make(chan struct{}, p.MaxConcurrencyPerHost)
+		p.sema[host] = sem
+	}
+	p.mu.Unlock()
+
+	sem <- struct{}{}
+	return func() { <-sem }
+}
+
+type policyFetcher struct {
+	policy *Policy
+	inner  fetcher.Fetcher
+	addr   string
+}
+
+func (f *policyFetcher) Fetch(ctx context.Context, addr string) (*fetcher.Response, error) {
+	p := f.policy
+
+	if p.RespectRobots {
+		allowed, err := p.robotsAllow(ctx, addr)
+		if err != nil {
+			glog.Warningf("policy: fetching robots.txt for %s: %v", addr, err)
+		} else if !allowed {
+			return nil, fmt.Errorf("policy: %s is disallowed by robots.txt", addr)
+		}
+	}
+
+	release := p.acquire(p.host(addr))
+	defer release()
+
+	var etag, lastModified string
+	if p.Store != nil {
+		if prev, err := p.Store.Read(addr); err == nil && prev.Header != nil {
+			etag = prev.Header.Get("ETag")
+			lastModified = prev.Header.Get("Last-Modified")
+		}
+	}
+
+	backoff := p.RetryBackoffBase
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var resp *fetcher.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if cf, ok := f.inner.(fetcher.ConditionalFetcher); ok {
+			resp, err = cf.FetchIfModified(ctx, addr, etag, lastModified)
+		} else {
+			resp, err = f.inner.Fetch(ctx, addr)
+		}
+
+		retryable := err != nil || (resp != nil && resp.StatusCode >= 500)
+		if !retryable {
+			return resp, err
+		}
+		if attempt >= p.RetryMax {
+			if err != nil {
+				return resp, err
+			}
+			return nil, fmt.Errorf("policy: %s: giving up after %d retries, last status %d", addr, p.RetryMax, resp.StatusCode)
+		}
+
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff)+1))
+		glog.V(1).Infof("policy: retrying %s in %v (attempt %d/%d)", addr, sleep, attempt+1, p.RetryMax)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sleep):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,134 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/the80srobot/watchurl/pkg/fetcher"
+	"github.com/the80srobot/watchurl/pkg/store"
+)
+
+// fakeFetcher returns responses/errors from a fixed queue, one per call, and
+// records how many times it was invoked.
+type fakeFetcher struct {
+	responses []*fetcher.Response
+	errs      []error
+	calls     int
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, addr string) (*fetcher.Response, error) {
+	i := f.calls
+	f.calls++
+	var resp *fetcher.Response
+	if i < len(f.responses) {
+		resp = f.responses[i]
+	}
+	var err error
+	if i < len(f.errs) {
+		err = f.errs[i]
+	}
+	return resp, err
+}
+
+func TestPolicyRetriesOn5xxThenSucceeds(t *testing.T) {
+	inner := &fakeFetcher{responses: []*fetcher.Response{
+		{StatusCode: 503},
+		{StatusCode: 503},
+		{StatusCode: 200, Body: []byte("ok")},
+	}}
+	p := &Policy{RetryMax: 2, RetryBackoffBase: time.Millisecond}
+	f := p.Wrap("https://example.com/a", inner)
+
+	resp, err := f.Fetch(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+	if inner.calls != 3 {
+		t.Errorf("calls = %d, want 3", inner.calls)
+	}
+}
+
+func TestPolicyReturnsErrorAfterExhaustingRetriesOn5xx(t *testing.T) {
+	inner := &fakeFetcher{responses: []*fetcher.Response{
+		{StatusCode: 500},
+		{StatusCode: 500},
+	}}
+	p := &Policy{RetryMax: 1, RetryBackoffBase: time.Millisecond}
+	f := p.Wrap("https://example.com/a", inner)
+
+	resp, err := f.Fetch(context.Background(), "https://example.com/a")
+	if err == nil {
+		t.Fatalf("Fetch: got nil error for a persistent 500, resp = %+v", resp)
+	}
+	if inner.calls != 2 {
+		t.Errorf("calls = %d, want 2 (initial attempt + %d retry)", inner.calls, p.RetryMax)
+	}
+}
+
+func TestPolicyDoesNotRetryNon5xx(t *testing.T) {
+	inner := &fakeFetcher{responses: []*fetcher.Response{{StatusCode: 404}}}
+	p := &Policy{RetryMax: 3, RetryBackoffBase: time.Millisecond}
+	f := p.Wrap("https://example.com/a", inner)
+
+	resp, err := f.Fetch(context.Background(), "https://example.com/a")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("StatusCode = %d, want 404", resp.StatusCode)
+	}
+	if inner.calls != 1 {
+		t.Errorf("calls = %d, want 1 (404 is not retryable)", inner.calls)
+	}
+}
+
+// TestPolicySendsConditionalGetThroughJSONBackend guards against a
+// fetcher.JSON/RSS backend silently skipping conditional GETs: both wrap an
+// *HTTP as a named field rather than embedding it, so they only forward
+// FetchIfModified if they implement it themselves.
+func TestPolicySendsConditionalGetThroughJSONBackend(t *testing.T) {
+	var gets int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gets++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+
+	st, err := store.New(t.TempDir(), store.FormatText)
+	if err != nil {
+		t.Fatalf("store.New: %v", err)
+	}
+	p := &Policy{Store: st, RetryBackoffBase: time.Millisecond}
+	inner := &fetcher.JSON{HTTP: &fetcher.HTTP{Client: srv.Client()}}
+	f := p.Wrap(srv.URL, inner)
+
+	resp, err := f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("first Fetch: %v", err)
+	}
+	if err := st.Write(srv.URL, store.Snapshot{Header: resp.Header}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	resp, err = f.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("second Fetch: %v", err)
+	}
+	if !resp.NotModified {
+		t.Errorf("second Fetch: NotModified = false, want true (conditional GET not forwarded through JSON backend)")
+	}
+	if gets != 2 {
+		t.Errorf("server got %d requests, want 2", gets)
+	}
+}
@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// robotsRules is the subset of a robots.txt that applies to watchurl: the
+// Disallow paths for the "*" user agent (watchurl does not send a distinct
+// User-Agent to warrant anything more specific).
+type robotsRules struct {
+	disallow []string
+}
+
+func (r *robotsRules) allows(path string) bool {
+	for _, prefix := range r.disallow {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// robotsAllow reports whether addr may be fetched, fetching and caching
+// robots.txt for its host on first use.
+func (p *Policy) robotsAllow(ctx context.Context, addr string) (bool, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	if p.robot == nil {
+		p.robot = map[string]*robotsRules{}
+	}
+	rules, cached := p.robot[u.Host]
+	p.mu.Unlock()
+	if !cached {
+		rules, err = fetchRobots(ctx, u)
+		if err != nil {
+			return true, err
+		}
+		p.mu.Lock()
+		p.robot[u.Host] = rules
+		p.mu.Unlock()
+	}
+
+	return rules.allows(u.Path), nil
+}
+
+func fetchRobots(ctx context.Context, target *url.URL) (*robotsRules, error) {
+	robotsURL := &url.URL{Scheme: target.Scheme, Host: target.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// No robots.txt, or the host is unreachable: be permissive rather
+		// than blocking every fetch because of a transient error.
+		return &robotsRules{}, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}, nil
+	}
+	return parseRobots(resp.Body)
+}
+
+// parseRobots extracts the Disallow rules that apply to "*", the only user
+// agent watchurl identifies as.
+func parseRobots(r io.Reader) (*robotsRules, error) {
+	rules := &robotsRules{}
+	applies := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			applies = value == "*"
+		case "disallow":
+			if applies && value != "" {
+				rules.disallow = append(rules.disallow, value)
+			}
+		}
+	}
+	return rules, scanner.Err()
+}
@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRobotsDisallowForWildcardAgent(t *testing.T) {
+	const robotsTxt = `
+User-agent: Googlebot
+Disallow: /only-google
+
+User-agent: *
+Disallow: /private
+Disallow: /admin
+`
+	rules, err := parseRobots(strings.NewReader(robotsTxt))
+	if err != nil {
+		t.Fatalf("parseRobots: %v", err)
+	}
+
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/only-google", true}, // only disallowed for Googlebot, not "*"
+		{"/private", false},
+		{"/private/sub", false},
+		{"/admin", false},
+		{"/public", true},
+	}
+	for _, c := range cases {
+		if got := rules.allows(c.path); got != c.want {
+			t.Errorf("allows(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestParseRobotsNoWildcardAgentAllowsEverything(t *testing.T) {
+	const robotsTxt = `
+User-agent: Googlebot
+Disallow: /
+`
+	rules, err := parseRobots(strings.NewReader(robotsTxt))
+	if err != nil {
+		t.Fatalf("parseRobots: %v", err)
+	}
+	if !rules.allows("/anything") {
+		t.Error("allows(/anything) = false, want true: rules only target Googlebot, not *")
+	}
+}
+
+func TestParseRobotsIgnoresCommentsAndBlankLines(t *testing.T) {
+	const robotsTxt = `
+# comment
+User-agent: *
+
+Disallow: /secret
+`
+	rules, err := parseRobots(strings.NewReader(robotsTxt))
+	if err != nil {
+		t.Fatalf("parseRobots: %v", err)
+	}
+	if rules.allows("/secret") {
+		t.Error("allows(/secret) = true, want false")
+	}
+}
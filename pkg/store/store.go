@@ -0,0 +1,154 @@
+// Package store persists snapshots of each watched URL, so that watchurl can
+// diff against the last one on the next fetch. Two formats are available:
+// plain text files holding only the latest snapshot, and WARC-inspired
+// archives that additionally keep the full history (see Format).
+//
+// The WARC formats are not interoperable with real WARC: a conforming
+// "response" record's block is a full HTTP message (status line, headers,
+// a blank line, then the body), whereas warcStore writes the headers as
+// non-standard WARC-X-Header-* fields and the body on its own, to avoid
+// re-serializing an http.Header into a wire-format HTTP response and
+// parsing it back out. Tools built for real WARC corpora (warcio, the
+// Wayback Machine's tooling) will not read these files; see the warcRecord
+// doc comment in warc.go for the exact shape this package does write.
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Format selects the on-disk representation used by New.
+type Format string
+
+const (
+	// FormatText stores only the latest snapshot, as a plain text file.
+	// This is the original, pre-WARC behaviour.
+	FormatText Format = "text"
+	// FormatWarc appends every snapshot as a pair of uncompressed WARC
+	// records: the raw fetch (status, headers, body) and the
+	// extracted/normalized text diffed against it.
+	FormatWarc Format = "warc"
+	// FormatWarcGz is FormatWarc with each record individually gzipped.
+	FormatWarcGz Format = "warc.gz"
+)
+
+// Snapshot is the content of a single fetch, as stored by a Store.
+type Snapshot struct {
+	// Text is the already-extracted (and, if configured, normalized)
+	// content diffed against the previous Snapshot.
+	Text      string
+	FetchedAt time.Time
+	// Header carries response metadata worth keeping alongside the
+	// snapshot, in particular ETag and Last-Modified, which pkg/policy uses
+	// for conditional GETs.
+	Header http.Header
+	// StatusCode is the backend-specific status code of the fetch that
+	// produced this snapshot (see fetcher.Response.StatusCode).
+	StatusCode int
+	// Body is the raw, unprocessed payload the fetch returned, before
+	// extraction or normalization. FormatWarc/FormatWarcGz archive it
+	// alongside Text; FormatText discards it.
+	Body []byte
+}
+
+// Store persists snapshots of watched URLs under a directory, one file per
+// URL (plus, for FormatText, a .meta.json file holding the headers), named
+// by the URL's SHA1 digest (to keep the name filesystem-safe and of bounded
+// length) plus a sanitized copy of the URL for readability.
+type Store interface {
+	// Write appends (or, for FormatText, overwrites with) the latest
+	// snapshot for addr.
+	Write(addr string, snap Snapshot) error
+	// Read returns the latest snapshot for addr. It returns an error
+	// satisfying os.IsNotExist if there isn't one yet.
+	Read(addr string) (Snapshot, error)
+	// History returns every snapshot stored for addr, oldest first, or nil
+	// if there is none. Stores that only keep the latest snapshot
+	// (FormatText) return at most a single element.
+	History(addr string) ([]Snapshot, error)
+}
+
+// New returns a Store of the given format, rooted at dir. A leading "~" in
+// dir is expanded to the user's home directory.
+func New(dir string, format Format) (Store, error) {
+	if strings.HasPrefix(dir, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = strings.Replace(dir, "~", home, 1)
+	}
+
+	switch format {
+	case "", FormatText:
+		return &textStore{dir: dir}, nil
+	case FormatWarc:
+		return &warcStore{dir: dir, gzipped: false}, nil
+	case FormatWarcGz:
+		return &warcStore{dir: dir, gzipped: true}, nil
+	default:
+		return nil, fmt.Errorf("store: unknown archive format %q", format)
+	}
+}
+
+var specialRE = regexp.MustCompile(`[^\w]+`)
+
+func pathFor(dir, addr, ext string) string {
+	digest := sha1.Sum([]byte(addr))
+	name := fmt.Sprintf("%s_%s%s", hex.EncodeToString(digest[:]), specialRE.ReplaceAllLiteralString(addr, "_"), ext)
+	return filepath.Join(dir, name)
+}
+
+type textStore struct {
+	dir string
+}
+
+func (s *textStore) Write(addr string, snap Snapshot) error {
+	name := pathFor(s.dir, addr, "")
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(name, []byte(snap.Text), 0644); err != nil {
+		return err
+	}
+	if len(snap.Header) == 0 {
+		return nil
+	}
+	meta, err := json.Marshal(snap.Header)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pathFor(s.dir, addr, ".meta.json"), meta, 0644)
+}
+
+func (s *textStore) Read(addr string) (Snapshot, error) {
+	p, err := os.ReadFile(pathFor(s.dir, addr, ""))
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := Snapshot{Text: string(p)}
+	if meta, err := os.ReadFile(pathFor(s.dir, addr, ".meta.json")); err == nil {
+		json.Unmarshal(meta, &snap.Header)
+	}
+	return snap, nil
+}
+
+func (s *textStore) History(addr string) ([]Snapshot, error) {
+	snap, err := s.Read(addr)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []Snapshot{snap}, nil
+}
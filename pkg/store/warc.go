@@ -0,0 +1,194 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// warcRecord is a single record in this package's WARC-inspired format.
+// warcStore writes two concurrent records per fetch: a "response" record
+// holding the raw, unprocessed fetch (so the archive can reconstruct what
+// the server actually returned), and a "metadata" record holding the
+// extracted/normalized text that's actually diffed, cross-referenced to the
+// response record via ConcurrentTo.
+//
+// This deviates from WARC/1.0 in one deliberate way: a real "response"
+// record's block is the verbatim HTTP response (status line, headers,
+// blank line, body), but writeWarcRecord instead writes the headers as
+// WARC-X-Header-* record fields and the body as the whole block, so that
+// writing a record never requires re-encoding an http.Header back into
+// wire format. Files written here are not parseable by warcio or other
+// real-WARC tooling.
+type warcRecord struct {
+	Type         string // "response" or "metadata"
+	RecordID     string
+	ConcurrentTo string // WARC-Concurrent-To; set on metadata records
+	TargetURI    string
+	Date         time.Time
+	StatusCode   int // meaningful on response records only
+	Header       http.Header
+	Body         []byte
+}
+
+// writeWarcRecord appends rec to w in WARC/1.0 format. If gzipped is true,
+// the record is written as its own gzip member, so that a file made up of
+// many such records can still be decompressed as a single gzip stream
+// (concatenated gzip members) while remaining scannable record-by-record.
+func writeWarcRecord(w io.Writer, rec warcRecord, gzipped bool) error {
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "WARC/1.0\r\n")
+	fmt.Fprintf(&body, "WARC-Type: %s\r\n", rec.Type)
+	fmt.Fprintf(&body, "WARC-Record-ID: %s\r\n", rec.RecordID)
+	if rec.ConcurrentTo != "" {
+		fmt.Fprintf(&body, "WARC-Concurrent-To: %s\r\n", rec.ConcurrentTo)
+	}
+	fmt.Fprintf(&body, "WARC-Target-URI: %s\r\n", rec.TargetURI)
+	fmt.Fprintf(&body, "WARC-Date: %s\r\n", rec.Date.UTC().Format(time.RFC3339))
+	if rec.Type == "response" {
+		fmt.Fprintf(&body, "WARC-X-Status-Code: %d\r\n", rec.StatusCode)
+		fmt.Fprintf(&body, "Content-Type: application/http; msgtype=response\r\n")
+	} else {
+		fmt.Fprintf(&body, "Content-Type: text/plain\r\n")
+	}
+	fmt.Fprintf(&body, "Content-Length: %d\r\n", len(rec.Body))
+	for k, vs := range rec.Header {
+		for _, v := range vs {
+			fmt.Fprintf(&body, "WARC-X-Header-%s: %s\r\n", k, v)
+		}
+	}
+	fmt.Fprintf(&body, "\r\n")
+	body.Write(rec.Body)
+	fmt.Fprintf(&body, "\r\n\r\n")
+
+	if !gzipped {
+		_, err := w.Write(body.Bytes())
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(body.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readWarcRecords reads back every record appended by writeWarcRecord,
+// oldest first.
+func readWarcRecords(r io.Reader, gzipped bool) ([]warcRecord, error) {
+	if gzipped {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil, nil
+			}
+			return nil, err
+		}
+		gz.Multistream(true)
+		r = gz
+	}
+
+	br := bufio.NewReader(r)
+	var records []warcRecord
+	for {
+		rec, err := readOneWarcRecord(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func readOneWarcRecord(br *bufio.Reader) (warcRecord, error) {
+	line, err := br.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return warcRecord{}, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return warcRecord{}, err
+	}
+	if strings.TrimSpace(line) != "WARC/1.0" {
+		return warcRecord{}, fmt.Errorf("store: malformed WARC record, want WARC/1.0, got %q", line)
+	}
+
+	rec := warcRecord{Header: http.Header{}}
+	contentLength := 0
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return warcRecord{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "WARC-Type":
+			rec.Type = value
+		case "WARC-Record-ID":
+			rec.RecordID = value
+		case "WARC-Concurrent-To":
+			rec.ConcurrentTo = value
+		case "WARC-Target-URI":
+			rec.TargetURI = value
+		case "WARC-Date":
+			rec.Date, _ = time.Parse(time.RFC3339, value)
+		case "WARC-X-Status-Code":
+			rec.StatusCode, _ = strconv.Atoi(value)
+		case "Content-Length":
+			contentLength, _ = strconv.Atoi(value)
+		default:
+			if name, ok := strings.CutPrefix(key, "WARC-X-Header-"); ok {
+				rec.Header.Add(name, value)
+			}
+		}
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return warcRecord{}, err
+	}
+	rec.Body = body
+
+	// writeWarcRecord ends the content block with a CRLF, then writes
+	// another CRLF as the blank-line separator before the next record; both
+	// need to be consumed here.
+	for i := 0; i < 2; i++ {
+		if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+			return warcRecord{}, err
+		}
+	}
+
+	return rec, nil
+}
+
+func warcRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// sortByDate sorts records oldest first; readWarcRecords already returns
+// them in append order, but this guards against out-of-order writes (e.g.
+// clock skew between runs).
+func sortByDate(records []warcRecord) {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Date.Before(records[j].Date) })
+}
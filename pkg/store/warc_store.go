@@ -0,0 +1,120 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// warcStore appends a WARC response record and a linked metadata record per
+// fetch, so the full history of a URL is recoverable, not just the latest
+// snapshot.
+type warcStore struct {
+	dir     string
+	gzipped bool
+}
+
+func (s *warcStore) ext() string {
+	if s.gzipped {
+		return ".warc.gz"
+	}
+	return ".warc"
+}
+
+func (s *warcStore) path(addr string) string {
+	return pathFor(s.dir, addr, s.ext())
+}
+
+func (s *warcStore) Write(addr string, snap Snapshot) error {
+	name := s.path(addr)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fetchedAt := snap.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	// Archive the raw, unprocessed fetch as a "response" record, so the
+	// corpus can reconstruct what the server actually returned, then the
+	// extracted/normalized text actually diffed as a linked "metadata"
+	// record.
+	respID := warcRecordID()
+	if err := writeWarcRecord(f, warcRecord{
+		Type:       "response",
+		RecordID:   respID,
+		TargetURI:  addr,
+		Date:       fetchedAt,
+		StatusCode: snap.StatusCode,
+		Header:     snap.Header,
+		Body:       snap.Body,
+	}, s.gzipped); err != nil {
+		return err
+	}
+	return writeWarcRecord(f, warcRecord{
+		Type:         "metadata",
+		RecordID:     warcRecordID(),
+		ConcurrentTo: respID,
+		TargetURI:    addr,
+		Date:         fetchedAt,
+		Body:         []byte(snap.Text),
+	}, s.gzipped)
+}
+
+// Read returns the most recent record for addr.
+func (s *warcStore) Read(addr string) (Snapshot, error) {
+	history, err := s.History(addr)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(history) == 0 {
+		return Snapshot{}, os.ErrNotExist
+	}
+	return history[len(history)-1], nil
+}
+
+func (s *warcStore) History(addr string) ([]Snapshot, error) {
+	f, err := os.Open(s.path(addr))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := readWarcRecords(f, s.gzipped)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(map[string]warcRecord, len(records)/2)
+	var metadata []warcRecord
+	for _, rec := range records {
+		switch rec.Type {
+		case "response":
+			responses[rec.RecordID] = rec
+		case "metadata":
+			metadata = append(metadata, rec)
+		}
+	}
+	sortByDate(metadata)
+
+	snapshots := make([]Snapshot, len(metadata))
+	for i, rec := range metadata {
+		snap := Snapshot{Text: string(rec.Body), FetchedAt: rec.Date}
+		if resp, ok := responses[rec.ConcurrentTo]; ok {
+			snap.Header = resp.Header
+			snap.StatusCode = resp.StatusCode
+			snap.Body = resp.Body
+		}
+		snapshots[i] = snap
+	}
+	return snapshots, nil
+}
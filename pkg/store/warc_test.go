@@ -0,0 +1,91 @@
+package store
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWarcStoreWriteHistory(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		s := &warcStore{dir: t.TempDir(), gzipped: gzipped}
+		addr := "https://example.com/a"
+
+		want := []Snapshot{
+			{
+				Text:       "first",
+				FetchedAt:  time.Unix(1, 0).UTC(),
+				Header:     http.Header{"Etag": {"a"}},
+				StatusCode: 200,
+				Body:       []byte("<html>first</html>"),
+			},
+			{
+				Text:       "second",
+				FetchedAt:  time.Unix(2, 0).UTC(),
+				Header:     http.Header{"Etag": {"b"}},
+				StatusCode: 404,
+				Body:       []byte("<html>not found</html>"),
+			},
+		}
+		for _, snap := range want {
+			if err := s.Write(addr, snap); err != nil {
+				t.Fatalf("gzipped=%v: Write: %v", gzipped, err)
+			}
+		}
+
+		got, err := s.History(addr)
+		if err != nil {
+			t.Fatalf("gzipped=%v: History: %v", gzipped, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("gzipped=%v: got %d snapshots, want %d", gzipped, len(got), len(want))
+		}
+		for i, snap := range got {
+			if snap.Text != want[i].Text || !snap.FetchedAt.Equal(want[i].FetchedAt) {
+				t.Errorf("gzipped=%v: snapshot %d = %+v, want %+v", gzipped, i, snap, want[i])
+			}
+			if snap.StatusCode != want[i].StatusCode {
+				t.Errorf("gzipped=%v: snapshot %d StatusCode = %d, want %d", gzipped, i, snap.StatusCode, want[i].StatusCode)
+			}
+			if string(snap.Body) != string(want[i].Body) {
+				t.Errorf("gzipped=%v: snapshot %d Body = %q, want %q", gzipped, i, snap.Body, want[i].Body)
+			}
+			if snap.Header.Get("Etag") != want[i].Header.Get("Etag") {
+				t.Errorf("gzipped=%v: snapshot %d Etag = %q, want %q", gzipped, i, snap.Header.Get("Etag"), want[i].Header.Get("Etag"))
+			}
+		}
+	}
+}
+
+func TestWarcRecordRoundTrip(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		var buf bytes.Buffer
+		want := []warcRecord{
+			{TargetURI: "https://example.com/a", Date: time.Unix(1, 0).UTC(), Header: http.Header{"Etag": {"a"}}, Body: []byte("first")},
+			{TargetURI: "https://example.com/a", Date: time.Unix(2, 0).UTC(), Header: http.Header{"Etag": {"b"}}, Body: []byte("second")},
+			{TargetURI: "https://example.com/a", Date: time.Unix(3, 0).UTC(), Header: http.Header{"Etag": {"c"}}, Body: []byte("third")},
+		}
+		for _, rec := range want {
+			if err := writeWarcRecord(&buf, rec, gzipped); err != nil {
+				t.Fatalf("gzipped=%v: writeWarcRecord: %v", gzipped, err)
+			}
+		}
+
+		got, err := readWarcRecords(&buf, gzipped)
+		if err != nil {
+			t.Fatalf("gzipped=%v: readWarcRecords: %v", gzipped, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("gzipped=%v: got %d records, want %d", gzipped, len(got), len(want))
+		}
+		for i, rec := range got {
+			if rec.TargetURI != want[i].TargetURI || !rec.Date.Equal(want[i].Date) || string(rec.Body) != string(want[i].Body) {
+				t.Errorf("gzipped=%v: record %d = %+v, want %+v", gzipped, i, rec, want[i])
+			}
+			if rec.Header.Get("Etag") != want[i].Header.Get("Etag") {
+				t.Errorf("gzipped=%v: record %d Etag = %q, want %q", gzipped, i, rec.Header.Get("Etag"), want[i].Header.Get("Etag"))
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package watcher
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// Algorithm selects how ReportDiff renders a change, via --diff-algorithm.
+type Algorithm string
+
+const (
+	// AlgoDMP is the original ANSI-colored, character-level diff from
+	// diffmatchpatch. It's the most readable in a terminal, but its output
+	// isn't meant to be parsed by another program.
+	AlgoDMP Algorithm = "dmp"
+	// AlgoUnified renders a patch-style, line-level diff ("-"/"+"/" "
+	// prefixes, no ANSI), suitable for scripting or feeding to patch(1).
+	AlgoUnified Algorithm = "unified"
+	// AlgoLine is a line-level diff with the same ANSI coloring as AlgoDMP.
+	AlgoLine Algorithm = "line"
+	// AlgoWord is a word-level diff with the same ANSI coloring as AlgoDMP.
+	AlgoWord Algorithm = "word"
+)
+
+// ReportDiff writes a diff between old and new to w, using algo, and
+// returns the number of insert/delete edits found.
+func ReportDiff(algo Algorithm, old, new string, w io.Writer) (int, error) {
+	switch algo {
+	case "", AlgoDMP:
+		return ReportDiffs(old, new, w), nil
+	case AlgoUnified:
+		return unifiedDiff(old, new, w), nil
+	case AlgoLine:
+		return coloredTokenDiff(old, new, w, splitLines)
+	case AlgoWord:
+		return coloredTokenDiff(old, new, w, splitWords)
+	default:
+		return 0, fmt.Errorf("watcher: unknown diff algorithm %q", algo)
+	}
+}
+
+var wordRE = regexp.MustCompile(`\s+|\S+`)
+
+func splitWords(text string) []string { return wordRE.FindAllString(text, -1) }
+
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// tokenDiffs runs diffmatchpatch at the granularity of tokenize's output
+// (lines or words) instead of individual characters, using the same
+// token-to-rune encoding trick diffmatchpatch itself uses for line mode.
+func tokenDiffs(old, new string, tokenize func(string) []string) []diffmatchpatch.Diff {
+	tokenArray := []string{""}
+	tokenIndex := map[string]int{}
+
+	encode := func(text string) string {
+		var sb strings.Builder
+		for _, tok := range tokenize(text) {
+			idx, ok := tokenIndex[tok]
+			if !ok {
+				tokenArray = append(tokenArray, tok)
+				idx = len(tokenArray) - 1
+				tokenIndex[tok] = idx
+			}
+			sb.WriteRune(rune(idx))
+		}
+		return sb.String()
+	}
+
+	chars1, chars2 := encode(old), encode(new)
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffMain(chars1, chars2, false)
+
+	expanded := make([]diffmatchpatch.Diff, len(diffs))
+	for i, d := range diffs {
+		var sb strings.Builder
+		for _, r := range d.Text {
+			sb.WriteString(tokenArray[r])
+		}
+		expanded[i] = diffmatchpatch.Diff{Type: d.Type, Text: sb.String()}
+	}
+	return expanded
+}
+
+// coloredTokenDiff writes an ANSI-colored diff at the given token
+// granularity (lines or words), in the same style as ReportDiffs.
+func coloredTokenDiff(old, new string, w io.Writer, tokenize func(string) []string) (int, error) {
+	edits := 0
+	for _, d := range tokenDiffs(old, new, tokenize) {
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			edits++
+			io.WriteString(w, "\x1b[32m"+d.Text+"\x1b[0m")
+		case diffmatchpatch.DiffDelete:
+			edits++
+			io.WriteString(w, "\x1b[31m"+d.Text+"\x1b[0m")
+		case diffmatchpatch.DiffEqual:
+			io.WriteString(w, d.Text)
+		}
+	}
+	return edits, nil
+}
+
+// unifiedDiff writes a patch(1)-compatible unified diff as a single hunk
+// covering both whole texts: every line is prefixed with " " (unchanged),
+// "-" (removed) or "+" (added).
+func unifiedDiff(old, new string, w io.Writer) int {
+	oldLines, newLines := len(splitLines(old)), len(splitLines(new))
+	oldStart, newStart := 1, 1
+	if oldLines == 0 {
+		oldStart = 0
+	}
+	if newLines == 0 {
+		newStart = 0
+	}
+	fmt.Fprintf(w, "--- old\n+++ new\n@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+	edits := 0
+	for _, d := range tokenDiffs(old, new, splitLines) {
+		prefix := " "
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			prefix = "+"
+		case diffmatchpatch.DiffDelete:
+			prefix = "-"
+		}
+		for _, line := range splitLines(d.Text) {
+			if d.Type != diffmatchpatch.DiffEqual {
+				edits++
+			}
+			fmt.Fprintf(w, "%s%s\n", prefix, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return edits
+}
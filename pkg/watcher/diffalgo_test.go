@@ -0,0 +1,95 @@
+package watcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReportDiffUnified(t *testing.T) {
+	var sb strings.Builder
+	edits, err := ReportDiff(AlgoUnified, "a\nb\nc\n", "a\nx\nc\n", &sb)
+	if err != nil {
+		t.Fatalf("ReportDiff: %v", err)
+	}
+	if edits != 2 {
+		t.Errorf("edits = %d, want 2", edits)
+	}
+	got := sb.String()
+	for _, want := range []string{"--- old", "+++ new", "-b", "+x", " a", " c"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestReportDiffLine(t *testing.T) {
+	var sb strings.Builder
+	edits, err := ReportDiff(AlgoLine, "a\nb\n", "a\nc\n", &sb)
+	if err != nil {
+		t.Fatalf("ReportDiff: %v", err)
+	}
+	if edits != 2 {
+		t.Errorf("edits = %d, want 2", edits)
+	}
+	got := sb.String()
+	if !strings.Contains(got, "\x1b[31m") || !strings.Contains(got, "\x1b[32m") {
+		t.Errorf("output missing ANSI delete/insert coloring:\n%q", got)
+	}
+}
+
+func TestReportDiffWord(t *testing.T) {
+	var sb strings.Builder
+	edits, err := ReportDiff(AlgoWord, "the quick fox", "the slow fox", &sb)
+	if err != nil {
+		t.Fatalf("ReportDiff: %v", err)
+	}
+	if edits != 2 {
+		t.Errorf("edits = %d, want 2", edits)
+	}
+}
+
+func TestReportDiffNoChanges(t *testing.T) {
+	for _, algo := range []Algorithm{AlgoDMP, AlgoUnified, AlgoLine, AlgoWord} {
+		var sb strings.Builder
+		edits, err := ReportDiff(algo, "same\n", "same\n", &sb)
+		if err != nil {
+			t.Fatalf("algo=%s: ReportDiff: %v", algo, err)
+		}
+		if edits != 0 {
+			t.Errorf("algo=%s: edits = %d, want 0", algo, edits)
+		}
+	}
+}
+
+func TestReportDiffUnknownAlgorithm(t *testing.T) {
+	var sb strings.Builder
+	if _, err := ReportDiff("bogus", "a", "b", &sb); err == nil {
+		t.Fatal("ReportDiff: got nil error for an unknown algorithm")
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	got := splitWords("the  quick fox")
+	want := []string{"the", "  ", "quick", " ", "fox"}
+	if len(got) != len(want) {
+		t.Fatalf("splitWords = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitWords[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	got := splitLines("a\nb\nc")
+	want := []string{"a\n", "b\n", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("splitLines = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitLines[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
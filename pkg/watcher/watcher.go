@@ -0,0 +1,223 @@
+// Package watcher implements the periodic fetch-extract-diff loop that is
+// the core of watchurl, driven by pluggable fetcher.Fetcher and
+// extract.Extractor backends.
+package watcher
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sergi/go-diff/diffmatchpatch"
+
+	"github.com/the80srobot/watchurl/pkg/eventlog"
+	"github.com/the80srobot/watchurl/pkg/extract"
+	"github.com/the80srobot/watchurl/pkg/fetcher"
+	"github.com/the80srobot/watchurl/pkg/normalize"
+	"github.com/the80srobot/watchurl/pkg/store"
+)
+
+// Watcher diffs the text behind a single URL against its last snapshot on
+// each tick and reports the result.
+type Watcher struct {
+	Fetcher   fetcher.Fetcher
+	Extractor extract.Extractor
+	Store     store.Store
+
+	// Normalizer, if set, cleans up the fetched content before it's diffed,
+	// so that boilerplate or volatile fragments don't show up as changes.
+	Normalizer *normalize.Pipeline
+
+	// DiffAlgorithm selects how diffs are rendered (see --diff-algorithm).
+	// The zero value is AlgoDMP.
+	DiffAlgorithm Algorithm
+
+	// Notify is called whenever the content changed, with a human-readable
+	// diff and the number of edits ReportDiff found. It may be nil.
+	Notify func(addr, diff string, edits int)
+
+	// LogFullDiff writes the full diff to glog instead of stdout.
+	LogFullDiff bool
+
+	// Log, if set, receives a fetch_started/fetch_ok/diff/error event for
+	// every tick, so that watchurl serve can stream and persist them.
+	Log *eventlog.Log
+}
+
+func (w *Watcher) emit(e eventlog.Event) {
+	if w.Log == nil {
+		return
+	}
+	if _, err := w.Log.Append(e); err != nil {
+		glog.Warningf("Appending to event log: %v", err)
+	}
+}
+
+// Watch diffs the text of the website at addr against the last snapshot and
+// reports any updates. If every is positive, Watch keeps running until ctx
+// is cancelled, checking once every interval (plus pseudo-random jitter up
+// to the value of jitter).
+func (w *Watcher) Watch(ctx context.Context, addr string, every, jitter time.Duration) {
+	t := time.NewTimer(time.Nanosecond)
+	defer t.Stop()
+
+	if jitter > 0 && every > 0 {
+		rand.Seed(time.Now().UnixNano())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if every > 0 {
+				var j time.Duration
+				if jitter > 0 {
+					j = time.Duration(rand.Int63n(int64(jitter/time.Nanosecond))) * time.Nanosecond
+				}
+				t.Reset(every + j)
+				glog.V(2).Infof("Fetching %s, then next fetch in %v + %v/%v jitter", addr, every, j, jitter)
+			}
+
+			w.emit(eventlog.Event{Type: eventlog.FetchStarted, URL: addr})
+			diff, edits, err := w.diffURL(ctx, addr)
+			if err != nil {
+				glog.Warningf("Checking %s: %v", addr, err)
+				w.emit(eventlog.Event{Type: eventlog.Error, URL: addr, Error: err.Error()})
+				continue
+			}
+			if diff != "" {
+				if w.LogFullDiff {
+					glog.Infof("Site %s updated (%d edits):\n%s", addr, edits, diff)
+				} else {
+					// Avoid writing the full output to both stdout and log.
+					fmt.Printf("Site %s diff:\n%s\n", addr, diff)
+					glog.Infof("Site %s updated (%d edits)", addr, edits)
+				}
+				if w.Notify != nil {
+					w.Notify(addr, diff, edits)
+				}
+			} else {
+				glog.V(1).Infof("No change in %s", addr)
+			}
+
+			if every == 0 {
+				glog.Info("Bailing after a successful check (use --repeat-every to repeat automatically)")
+				return
+			}
+		}
+	}
+}
+
+// getSnapshot fetches and extracts addr's current content. notModified is
+// true when the Fetcher reported (via a conditional GET) that the content
+// hasn't changed since the snapshot already in the Store, in which case the
+// returned Snapshot's Text is empty and should be ignored.
+func (w *Watcher) getSnapshot(ctx context.Context, addr string) (snap store.Snapshot, notModified bool, err error) {
+	resp, err := w.Fetcher.Fetch(ctx, addr)
+	if err != nil {
+		return store.Snapshot{}, false, err
+	}
+	if resp.NotModified {
+		return store.Snapshot{}, true, nil
+	}
+	body, err := w.Normalizer.NormalizeBody(resp.Body)
+	if err != nil {
+		return store.Snapshot{}, false, err
+	}
+	text, err := w.Extractor.Extract(body)
+	if err != nil {
+		return store.Snapshot{}, false, err
+	}
+	text = w.Normalizer.NormalizeText(text)
+	return store.Snapshot{
+		Text:       text,
+		FetchedAt:  resp.FetchedAt,
+		Header:     resp.Header,
+		StatusCode: resp.StatusCode,
+		Body:       resp.Body,
+	}, false, nil
+}
+
+func (w *Watcher) diffURL(ctx context.Context, addr string) (string, int, error) {
+	prev, err := w.Store.Read(addr)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	snap, notModified, err := w.getSnapshot(ctx, addr)
+	if err != nil {
+		w.emit(eventlog.Event{Type: eventlog.Error, URL: addr, Error: err.Error()})
+		return "", 0, nil
+	}
+	if notModified {
+		w.emit(eventlog.Event{Type: eventlog.FetchOK, URL: addr, Hash: "not-modified"})
+		return "", 0, nil
+	}
+	hash := sha1.Sum([]byte(snap.Text))
+	w.emit(eventlog.Event{Type: eventlog.FetchOK, URL: addr, Hash: hex.EncodeToString(hash[:])})
+
+	if prev.Text == snap.Text {
+		return "", 0, nil
+	}
+
+	if err := w.Store.Write(addr, snap); err != nil {
+		return "", 0, err
+	}
+
+	if prev.Text == "" {
+		return "(initial fetch)", 0, nil
+	}
+
+	var sb strings.Builder
+	edits, err := ReportDiff(w.DiffAlgorithm, prev.Text, snap.Text, &sb)
+	if err != nil {
+		return "", 0, err
+	}
+	w.emit(eventlog.Event{Type: eventlog.Diff, URL: addr, Edits: edits, Diff: sb.String()})
+	return sb.String(), edits, nil
+}
+
+// ReportDiffs writes a human-readable, ANSI-colored diff between old and new
+// to w and returns the number of insert/delete edits found.
+func ReportDiffs(old, new string, w io.Writer) int {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(old, new, true))
+	edits := 0
+
+	for _, diff := range diffs {
+		switch diff.Type {
+		case diffmatchpatch.DiffInsert:
+			edits++
+			io.WriteString(w, "\x1b[32m")
+			io.WriteString(w, diff.Text)
+			io.WriteString(w, "\x1b[0m")
+		case diffmatchpatch.DiffDelete:
+			edits++
+			io.WriteString(w, "\x1b[31m")
+			io.WriteString(w, diff.Text)
+			io.WriteString(w, "\x1b[0m")
+		case diffmatchpatch.DiffEqual:
+			firstNL := strings.IndexByte(diff.Text, '\n')
+			lastNL := strings.LastIndexByte(diff.Text, '\n')
+			if lastNL == firstNL {
+				io.WriteString(w, diff.Text)
+			} else {
+				io.WriteString(w, diff.Text[:firstNL])
+				fmt.Fprintf(w, "\n(skipped %d bytes)\n", lastNL-firstNL)
+				io.WriteString(w, diff.Text[lastNL:])
+			}
+		}
+	}
+	return edits
+}
@@ -1,3 +1,7 @@
+// Command watchurl periodically fetches a set of URLs, extracts their
+// content and notifies the user when it changes. See pkg/fetcher and
+// pkg/extract for the pluggable backends, and pkg/watcher for the
+// fetch-diff-notify loop.
 package main
 
 import (
@@ -7,12 +11,11 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -20,8 +23,17 @@ import (
 
 	"github.com/andybrewer/mack"
 	"github.com/golang/glog"
-	"github.com/sergi/go-diff/diffmatchpatch"
-	"jaytaylor.com/html2text"
+
+	"github.com/the80srobot/watchurl/pkg/api"
+	"github.com/the80srobot/watchurl/pkg/daemon"
+	"github.com/the80srobot/watchurl/pkg/eventlog"
+	"github.com/the80srobot/watchurl/pkg/extract"
+	"github.com/the80srobot/watchurl/pkg/fetcher"
+	"github.com/the80srobot/watchurl/pkg/normalize"
+	"github.com/the80srobot/watchurl/pkg/notify"
+	"github.com/the80srobot/watchurl/pkg/policy"
+	"github.com/the80srobot/watchurl/pkg/store"
+	"github.com/the80srobot/watchurl/pkg/watcher"
 )
 
 var (
@@ -30,17 +42,77 @@ var (
 	jitter      = flag.Duration("jitter", 5*time.Minute, "random jitter, if --repeat-every is used")
 	macNotify   = flag.Bool("macos-notify", false, "(macOS only) display a desktop notification when updated")
 	logFullDiff = flag.Bool("log-full-diff", false, "Write the full diff to glog (otherwise write it to stdout)")
+
+	fetcherName   = flag.String("fetcher", "", "backend used to retrieve content: http, rss, json, chrome (default: picked from the URL scheme)")
+	extractorName = flag.String("extractor", "html2text", "backend used to turn fetched content into text: html2text, selector, xpath, regex")
+	selector      = flag.String("selector", "", "CSS selector, XPath expression or regex used by the selector/xpath/regex extractors")
+
+	archiveFormat = flag.String("archive-format", "text", "snapshot storage format: text (latest snapshot only), warc or warc.gz (full history)")
+
+	serveAddr = flag.String("addr", ":8080", "address for 'watchurl serve' to listen on")
+
+	configPath = flag.String("config", "~/.watchurl/config.yaml", "YAML file routing site updates to notify.Sink backends (webhook, SMTP, Slack, ...)")
+
+	respectRobots         = flag.Bool("respect-robots", false, "skip URLs disallowed by their host's robots.txt")
+	maxConcurrencyPerHost = flag.Int("max-concurrency-per-host", 1, "maximum concurrent fetches to a single host (0 for unlimited)")
+	retryBackoffBase      = flag.Duration("retry-backoff-base", time.Second, "initial delay before retrying a failed fetch, doubling (plus jitter) each attempt")
+	retryMax              = flag.Int("retry-max", 3, "number of retries on a 5xx response or network error, after the first attempt")
+
+	diffAlgorithm = flag.String("diff-algorithm", "dmp", "how to render diffs: dmp (ANSI, character-level), unified (patch-style, scriptable), word or line (ANSI, token-level)")
 )
 
 func main() {
 	flag.Parse()
+	args := flag.Args()
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "history":
+			if err := runHistory(args[1:]); err != nil {
+				glog.Exitf("%v", err)
+			}
+			return
+		case "serve":
+			if err := runServe(args[1:]); err != nil {
+				glog.Exitf("%v", err)
+			}
+			return
+		case "tail":
+			if err := runTail(args[1:]); err != nil {
+				glog.Exitf("%v", err)
+			}
+			return
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	// Buffered to two values, because a SIGTERM might come from the system, or
 	// when all the goroutines finish, and we only drain the channel once, so
 	// one of those might theoretically block.
 	ch := make(chan os.Signal, 2)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
-	args := flag.Args()
+
+	st, err := store.New(*stateDir, store.Format(*archiveFormat))
+	if err != nil {
+		glog.Exitf("Setting up state dir: %v", err)
+	}
+	ex, err := extract.New(*extractorName, *selector)
+	if err != nil {
+		glog.Exitf("Setting up extractor: %v", err)
+	}
+	notifyCfg, err := loadNotifyConfig()
+	if err != nil {
+		glog.Exitf("Setting up notify config: %v", err)
+	}
+	router, err := notify.Build(notifyCfg)
+	if err != nil {
+		glog.Exitf("Setting up notify config: %v", err)
+	}
+	normalizers, err := buildNormalizers(notifyCfg)
+	if err != nil {
+		glog.Exitf("Setting up normalize config: %v", err)
+	}
+	pol := newPolicy(st)
 
 	// There are two ways to shut down. Firstly, if we get SIGTERM, then ch will
 	// unblock, we then cancel the context and wait for done to close. Secondly,
@@ -49,9 +121,30 @@ func main() {
 	var wg sync.WaitGroup
 	wg.Add(len(args))
 	for _, addr := range args {
+		fe, err := fetcher.New(addr, *fetcherName)
+		if err != nil {
+			glog.Exitf("Setting up fetcher for %s: %v", addr, err)
+		}
+		w := &watcher.Watcher{
+			Fetcher:       pol.Wrap(addr, fe),
+			Extractor:     ex,
+			Store:         st,
+			Normalizer:    normalizers[addr],
+			DiffAlgorithm: watcher.Algorithm(*diffAlgorithm),
+			LogFullDiff:   *logFullDiff,
+		}
+		w.Notify = func(addr, diff string, edits int) {
+			if *macNotify {
+				mack.Notify("Site updated", addr, fmt.Sprintf("%d edits (check console output)", edits), "Ping")
+			}
+			if err := router.Dispatch(ctx, notify.Event{URL: addr, Diff: diff, Edits: edits}); err != nil {
+				glog.Warningf("Notifying for %s: %v", addr, err)
+			}
+		}
+
 		go func(addr string) {
 			defer wg.Done()
-			watch(ctx, addr, *every, *jitter)
+			w.Watch(ctx, addr, *every, *jitter)
 		}(addr)
 	}
 
@@ -68,180 +161,263 @@ func main() {
 	<-done
 }
 
-// watch diffs the text of the website at the given url against the last
-// snapshot and notifies the user of any updates. If every is positive, then
-// watch will keep running until the context is cancelled, checking once every
-// interval (plust pseudo-random jitter up to the value of jitter).
-func watch(ctx context.Context, addr string, every, jitter time.Duration) {
-	t := time.NewTimer(time.Nanosecond)
-	defer t.Stop()
+// runHistory implements the "watchurl history <url> [<from> <to>]"
+// subcommand: with just a URL, it lists every snapshot's timestamp; with two
+// additional RFC3339 timestamps, it prints the diff between the snapshots
+// closest to (at or before) each one.
+func runHistory(args []string) error {
+	if len(args) != 1 && len(args) != 3 {
+		return fmt.Errorf("usage: watchurl history <url> [<from> <to>]")
+	}
 
-	if jitter > 0 && every > 0 {
-		rand.Seed(time.Now().UnixNano())
+	st, err := store.New(*stateDir, store.Format(*archiveFormat))
+	if err != nil {
+		return err
+	}
+	addr := args[0]
+	snapshots, err := st.History(addr)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no snapshots recorded for %s", addr)
 	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-t.C:
-			if every > 0 {
-				var j time.Duration
-				if jitter > 0 {
-					j = time.Duration(rand.Int63n(int64(jitter/time.Nanosecond))) * time.Nanosecond
-				}
-				t.Reset(every + j)
-				glog.V(2).Infof("Fetching %s, then next fetch in %v + %v/%v jitter", addr, every, j, jitter)
-			}
+	if len(args) == 1 {
+		for _, snap := range snapshots {
+			fmt.Printf("%s\n", snap.FetchedAt.Format(time.RFC3339))
+		}
+		return nil
+	}
 
-			diff, edits, err := diffURL(ctx, addr)
-			if err != nil {
-				glog.Warningf("Checking %s: %v", addr, err)
-				continue
-			}
-			if diff != "" {
-				if *logFullDiff {
-					glog.Infof("Site %s updated (%d edits):\n%s", addr, edits, diff)
-				} else {
-					// Avoid writing the full output to both stdout and log.
-					fmt.Printf("Site %s diff:\n%s\n", addr, diff)
-					glog.Infof("Site %s updated (%d edits)", addr, edits)
-				}
-				if *macNotify {
-					mack.Notify("Site updated", addr, fmt.Sprintf("%d edits (check console output)", edits), "Ping")
-				}
-
-			} else {
-				glog.V(1).Infof("No change in %s", addr)
-			}
+	from, err := time.Parse(time.RFC3339, args[1])
+	if err != nil {
+		return fmt.Errorf("parsing <from>: %w", err)
+	}
+	to, err := time.Parse(time.RFC3339, args[2])
+	if err != nil {
+		return fmt.Errorf("parsing <to>: %w", err)
+	}
 
-			if every == 0 {
-				glog.Info("Bailing after a successful check (use --repeat-every to repeat automatically)")
-				return
-			}
-		}
+	oldSnap, ok := snapshotAtOrBefore(snapshots, from)
+	if !ok {
+		return fmt.Errorf("no snapshot at or before %s", args[1])
+	}
+	newSnap, ok := snapshotAtOrBefore(snapshots, to)
+	if !ok {
+		return fmt.Errorf("no snapshot at or before %s", args[2])
 	}
-}
 
-var specialRE = regexp.MustCompile(`[^\w]+`)
+	var sb strings.Builder
+	if _, err := watcher.ReportDiff(watcher.Algorithm(*diffAlgorithm), oldSnap.Text, newSnap.Text, &sb); err != nil {
+		return err
+	}
+	fmt.Print(sb.String())
+	return nil
+}
 
-func statePath(addr string) (string, error) {
-	dir := *stateDir
-	if strings.HasPrefix(dir, "~") {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return "", err
+func snapshotAtOrBefore(snapshots []store.Snapshot, t time.Time) (store.Snapshot, bool) {
+	var best store.Snapshot
+	var found bool
+	for _, snap := range snapshots {
+		if snap.FetchedAt.After(t) {
+			continue
+		}
+		if !found || snap.FetchedAt.After(best.FetchedAt) {
+			best, found = snap, true
 		}
-		dir = strings.Replace(dir, "~", home, 1)
 	}
-
-	digest := sha1.Sum([]byte(addr))
-	name := fmt.Sprintf("%s_%s", hex.EncodeToString(digest[:]), specialRE.ReplaceAllLiteralString(addr, "_"))
-	return filepath.Join(dir, name), nil
+	return best, found
 }
 
-func writeState(addr, text string) error {
-	name, err := statePath(addr)
+// runServe implements "watchurl serve [url...]": a daemon that watches the
+// given URLs (if any), accepts more at runtime over the HTTP API, and
+// streams its event log at /logs.
+func runServe(initialURLs []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+
+	st, err := store.New(*stateDir, store.Format(*archiveFormat))
 	if err != nil {
-		return err
+		return fmt.Errorf("setting up state dir: %w", err)
 	}
-	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
-		return err
+	ex, err := extract.New(*extractorName, *selector)
+	if err != nil {
+		return fmt.Errorf("setting up extractor: %w", err)
 	}
-
-	return os.WriteFile(name, []byte(text), 0644)
-}
-
-func readState(addr string) (string, error) {
-	name, err := statePath(addr)
+	evDir, err := expandStateDir(filepath.Join(*stateDir, "events"))
 	if err != nil {
-		return "", err
+		return err
 	}
-	p, err := os.ReadFile(name)
+	elog, err := eventlog.Open(evDir)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("opening event log: %w", err)
 	}
-	return string(p), nil
-}
-
-func diffURL(ctx context.Context, addr string) (string, int, error) {
-	text, err := getURLText(ctx, addr)
+	notifyCfg, err := loadNotifyConfig()
 	if err != nil {
-		return "", 0, nil
+		return fmt.Errorf("setting up notify config: %w", err)
 	}
-	old, err := readState(addr)
-	if os.IsNotExist(err) {
-		err = nil
+	router, err := notify.Build(notifyCfg)
+	if err != nil {
+		return fmt.Errorf("setting up notify config: %w", err)
 	}
+	normalizers, err := buildNormalizers(notifyCfg)
 	if err != nil {
-		return "", 0, nil
+		return fmt.Errorf("setting up normalize config: %w", err)
 	}
 
-	if old == text {
-		return "", 0, nil
+	mgr := daemon.NewManager(daemon.Config{
+		Store:         st,
+		Extractor:     ex,
+		FetcherName:   *fetcherName,
+		Every:         *every,
+		Jitter:        *jitter,
+		LogFullDiff:   *logFullDiff,
+		DiffAlgorithm: watcher.Algorithm(*diffAlgorithm),
+		Log:           elog,
+		Policy:        newPolicy(st),
+		Normalizers:   normalizers,
+		Notify: func(addr, diff string, edits int) {
+			if *macNotify {
+				mack.Notify("Site updated", addr, fmt.Sprintf("%d edits (check console output)", edits), "Ping")
+			}
+			if err := router.Dispatch(context.Background(), notify.Event{URL: addr, Diff: diff, Edits: edits}); err != nil {
+				glog.Warningf("Notifying for %s: %v", addr, err)
+			}
+		},
+	})
+	for _, addr := range initialURLs {
+		if err := mgr.Add(ctx, addr); err != nil {
+			return err
+		}
 	}
 
-	if err := writeState(addr, text); err != nil {
-		return "", 0, err
-	}
+	srv := &http.Server{Addr: *serveAddr, Handler: (&api.Server{Manager: mgr, Log: elog}).Handler()}
+	srvErr := make(chan error, 1)
+	go func() { srvErr <- srv.ListenAndServe() }()
+	glog.Infof("Listening on %s", *serveAddr)
 
-	if old == "" {
-		return "(initial fetch)", 0, nil
+	select {
+	case <-ch:
+		glog.Info("Shutting down...")
+	case err := <-srvErr:
+		if err != http.ErrServerClosed {
+			return err
+		}
 	}
 
-	var sb strings.Builder
-	edits := reportDiffs(old, text, &sb)
-	return sb.String(), edits, nil
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	srv.Shutdown(shutdownCtx)
+	mgr.Shutdown()
+	return nil
 }
 
-func reportDiffs(old, new string, w io.Writer) int {
-	dmp := diffmatchpatch.New()
-	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(old, new, true))
-	edits := 0
-
-	for _, diff := range diffs {
-		switch diff.Type {
-		case diffmatchpatch.DiffInsert:
-			edits++
-			io.WriteString(w, "\x1b[32m")
-			io.WriteString(w, diff.Text)
-			io.WriteString(w, "\x1b[0m")
-		case diffmatchpatch.DiffDelete:
-			edits++
-			io.WriteString(w, "\x1b[31m")
-			io.WriteString(w, diff.Text)
-			io.WriteString(w, "\x1b[0m")
-		case diffmatchpatch.DiffEqual:
-			firstNL := strings.IndexByte(diff.Text, '\n')
-			lastNL := strings.LastIndexByte(diff.Text, '\n')
-			if lastNL == firstNL {
-				io.WriteString(w, diff.Text)
-			} else {
-				io.WriteString(w, diff.Text[:firstNL])
-				fmt.Fprintf(w, "\n(skipped %d bytes)\n", lastNL-firstNL)
-				io.WriteString(w, diff.Text[lastNL:])
-			}
-		}
+// runTail implements "watchurl tail <daemon-addr>": it follows the event
+// log streamed by watchurl serve, resuming from the last event it saw on a
+// previous run.
+func runTail(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: watchurl tail <http://host:port>")
 	}
-	return edits
-}
+	base := args[0]
 
-func getURLText(ctx context.Context, addr string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	posDir, err := expandStateDir(filepath.Join(*stateDir, "tail"))
 	if err != nil {
-		return "", err
+		return err
+	}
+	if err := os.MkdirAll(posDir, 0755); err != nil {
+		return err
+	}
+	digest := sha1.Sum([]byte(base))
+	posFile := filepath.Join(posDir, hex.EncodeToString(digest[:])+".last")
+
+	var after uint64
+	if b, err := os.ReadFile(posFile); err == nil {
+		after, _ = strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
 	}
-	resp, err := http.DefaultClient.Do(req)
+
+	resp, err := http.Get(fmt.Sprintf("%s/logs?after=%d", strings.TrimRight(base, "/"), after))
 	if err != nil {
-		return "", err
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /logs: %s", resp.Status)
+	}
+
+	for {
+		e, err := eventlog.ReadFramed(resp.Body)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s %s edits=%d hash=%s%s\n", e.Time.Format(time.RFC3339), e.Type, e.URL, e.Edits, e.Hash, errSuffix(e.Error))
+		if err := os.WriteFile(posFile, []byte(strconv.FormatUint(e.ID, 10)), 0644); err != nil {
+			return err
+		}
 	}
-	body, err := io.ReadAll(resp.Body)
+}
+
+func errSuffix(msg string) string {
+	if msg == "" {
+		return ""
+	}
+	return " error=" + msg
+}
+
+// expandStateDir expands a leading "~" the same way pkg/store does, for the
+// directories watchurl manages itself (events, tail positions).
+func expandStateDir(dir string) (string, error) {
+	if !strings.HasPrefix(dir, "~") {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	text, err := html2text.FromString(string(body), html2text.Options{OmitLinks: true})
+	return strings.Replace(dir, "~", home, 1), nil
+}
+
+// newPolicy builds the robots.txt/concurrency/retry policy shared by every
+// watched URL's fetcher, from the --respect-robots/--max-concurrency-per-host/
+// --retry-backoff-base/--retry-max flags.
+func newPolicy(st store.Store) *policy.Policy {
+	return &policy.Policy{
+		Store:                 st,
+		RespectRobots:         *respectRobots,
+		MaxConcurrencyPerHost: *maxConcurrencyPerHost,
+		RetryBackoffBase:      *retryBackoffBase,
+		RetryMax:              *retryMax,
+	}
+}
+
+// loadNotifyConfig reads --config, if it exists, which describes both the
+// notify.Sink routes and the per-URL normalize.Config used by
+// buildNormalizers.
+func loadNotifyConfig() (*notify.Config, error) {
+	path, err := expandStateDir(*configPath)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return notify.Load(path)
+}
+
+// buildNormalizers compiles each route's Normalize config into a Pipeline,
+// keyed by URL, so every Watcher can look up its own.
+func buildNormalizers(cfg *notify.Config) (map[string]*normalize.Pipeline, error) {
+	pipelines := make(map[string]*normalize.Pipeline, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		p, err := normalize.Build(route.Normalize)
+		if err != nil {
+			return nil, fmt.Errorf("normalize config for %s: %w", route.URL, err)
+		}
+		pipelines[route.URL] = p
 	}
-	return text, nil
+	return pipelines, nil
 }